@@ -0,0 +1,85 @@
+package plurals
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// EvalBig evaluates exp exactly for any n, number literals above
+// math.MaxInt64 included. `/` and `%` truncate toward zero, the same as the
+// int64 path. When n fits in int64 and exp can not overflow (no `+`, `-`,
+// `*`, no large literal), exp.Eval is used directly.
+func EvalBig(exp Expression, n *big.Int) (*big.Int, error) {
+	if n.IsInt64() && !mayOverflow(exp) {
+		val, err := exp.Eval(n.Int64())
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(val), nil
+	}
+	e := evaluator[*big.Int]{d: bigDomain{}, n: n}
+	return e.eval(exp)
+}
+
+// bigLiteral 返回超出 int64 的数字字面量, 忽略 C 的整数后缀
+func bigLiteral(s string) *big.Int {
+	s = strings.TrimRight(s, "uUlL")
+	if len(s) < 19 {
+		return nil
+	}
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok || i.IsInt64() {
+		return nil
+	}
+	return i
+}
+
+// EvalDecimal is EvalBig with n given as a decimal string.
+func EvalDecimal(exp Expression, n string) (*big.Int, error) {
+	i, ok := new(big.Int).SetString(n, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal number: %q", n)
+	}
+	return EvalBig(exp, i)
+}
+
+func mayOverflow(exp Expression) bool {
+	return !inspect(exp, func(e Expression) bool {
+		if p, ok := e.(*PrimaryNode); ok && p.Big != nil {
+			return false
+		}
+		if b, ok := e.(*BinaryNExp); ok {
+			for _, op := range b.Op {
+				if op == "+" || op == "-" || op == "*" {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+type bigDomain struct{}
+
+func (bigDomain) num(v int64) *big.Int { return big.NewInt(v) }
+
+func (bigDomain) literal(p *PrimaryNode) *big.Int {
+	if p.Big != nil {
+		return p.Big
+	}
+	return big.NewInt(p.Num)
+}
+
+func (bigDomain) bool(b bool) *big.Int { return big.NewInt(b2i(b)) }
+
+func (bigDomain) truth(v *big.Int) bool { return v.Sign() != 0 }
+
+// big.Int 的 Quo 与 Rem 向零截断, 与 int64 一致
+func (bigDomain) add(x, y *big.Int) *big.Int { return new(big.Int).Add(x, y) }
+func (bigDomain) sub(x, y *big.Int) *big.Int { return new(big.Int).Sub(x, y) }
+func (bigDomain) mul(x, y *big.Int) *big.Int { return new(big.Int).Mul(x, y) }
+func (bigDomain) quo(x, y *big.Int) *big.Int { return new(big.Int).Quo(x, y) }
+func (bigDomain) rem(x, y *big.Int) *big.Int { return new(big.Int).Rem(x, y) }
+
+func (bigDomain) cmp(x, y *big.Int) int { return x.Cmp(y) }
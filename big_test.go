@@ -0,0 +1,97 @@
+package plurals
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEvalDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		exp  string
+		n    string
+		want string
+		err  error
+		bad  bool
+	}{
+		{exp: "n != 1", n: "1", want: "0"},
+		{exp: "n != 1", n: "100000000000000000000000000001", want: "1"},
+		{exp: "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2",
+			n: "123456789012345678901234567891", want: "0"},
+		{exp: "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2",
+			n: "123456789012345678901234567822", want: "1"},
+		{exp: "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2",
+			n: "123456789012345678901234567811", want: "2"},
+		{exp: "n * 2 / 2", n: "9223372036854775807", want: "9223372036854775807"},
+		{exp: "n + 1 > n", n: "9223372036854775807", want: "1"},
+		{exp: "n % 7", n: "-100000000000000000000", want: "-2"},
+		{exp: "n / 7", n: "-15", want: "-2"},
+		{exp: "n / ( n - n )", n: "100000000000000000000", err: ErrDivideZero},
+		{exp: "n", n: "1x", bad: true},
+		{exp: "n == 18446744073709551617", n: "1", want: "0"}, // 字面量不截断
+		{exp: "n == 18446744073709551617", n: "18446744073709551617", want: "1"},
+		{exp: "100000000000000000000 / n", n: "3", want: "33333333333333333333"},
+	} {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.exp, err)
+		}
+		got, err := EvalDecimal(exp, tt.n)
+		if tt.bad {
+			if err == nil {
+				t.Errorf("%q n=%s: want error", tt.exp, tt.n)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q n=%s: err=%v, want %v", tt.exp, tt.n, err, tt.err)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("%q n=%s: got=%v, want=%v", tt.exp, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEvalBigMatchesInt64(t *testing.T) {
+	for s, f := range commons {
+		exp, _ := Compile(s)
+		for n := range int64(1000) {
+			for _, v := range []int64{n, -n} {
+				got, err := EvalBig(exp, big.NewInt(v))
+				if err != nil || got.Int64() != f(v) {
+					t.Errorf("%q n=%d: got=%v, err=%v, want=%d", s, v, got, err, f(v))
+					break
+				}
+			}
+		}
+	}
+	// 不走 int64 快速路径时结果一致
+	exp, _ := Compile("n % 100 - n % 10 * 3 + n / 7")
+	for n := range int64(1000) {
+		want, _ := exp.Eval(-n)
+		e := evaluator[*big.Int]{d: bigDomain{}, n: big.NewInt(-n)}
+		got, err := e.eval(exp)
+		if err != nil || got.Int64() != want {
+			t.Errorf("n=%d: got=%v, err=%v, want=%d", -n, got, err, want)
+		}
+	}
+}
+
+func TestDomainArith(t *testing.T) {
+	for _, op := range []string{"+", "-", "*", "/", "%"} {
+		for x := int64(-7); x <= 7; x++ {
+			for y := int64(-3); y <= 3; y++ {
+				want, wantErr := arith[int64](int64Domain{}, op, x, y)
+				got, err := arith[*big.Int](bigDomain{}, op, big.NewInt(x), big.NewInt(y))
+				if !errors.Is(err, wantErr) || err == nil && got.Int64() != want {
+					t.Errorf("%d %s %d: big=%v, %v, int64=%d, %v", x, op, y, got, err, want, wantErr)
+				}
+				gnu, err := arith[uint64](gnuDomain{}, op, uint64(x), uint64(y))
+				if !errors.Is(err, wantErr) || err == nil && op != "/" && op != "%" && gnu != uint64(want) {
+					t.Errorf("%d %s %d: gnu=%v, %v, int64=%d, %v", x, op, y, gnu, err, want, wantErr)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package plurals
+
+import (
+	"fmt"
+	"math"
+)
+
+// The builder functions construct expression trees directly, inserting
+// parentheses where needed, so that compiling the String() of a built tree
+// gives an equivalent expression. Trees using Var or In need Extended().
+// Like regexp.MustCompile, Var, In and NotIn panic on input that could not
+// be printed as valid syntax.
+
+// N is the variable n.
+func N() Expression {
+	return &PrimaryNode{Type: TokenTypeIDN}
+}
+
+// Var is a variable of the extended syntax. It panics if name is not an
+// identifier, or is a keyword or N.
+func Var(name string) Expression {
+	if name == "n" {
+		return N()
+	}
+	if name == "" || !isLetter(name[0]) || wordEnd(name, 0) != len(name) ||
+		name == "in" || name == "not" || name == "N" {
+		panic(fmt.Sprintf("plurals: invalid variable name %q", name))
+	}
+	return &PrimaryNode{Type: TokenTypeIDN, Name: name}
+}
+
+// Num is a number, negative numbers are written as `( 0 - v )`.
+func Num(v int64) Expression {
+	switch {
+	case v == math.MinInt64:
+		return paren(Sub(Num(-math.MaxInt64), Num(1)), len(levels))
+	case v < 0:
+		return paren(Sub(Num(0), Num(-v)), len(levels))
+	}
+	return num(v)
+}
+
+func Add(a, b Expression) Expression { return binary("+", a, b) }
+func Sub(a, b Expression) Expression { return binary("-", a, b) }
+func Mul(a, b Expression) Expression { return binary("*", a, b) }
+func Div(a, b Expression) Expression { return binary("/", a, b) }
+func Mod(a, b Expression) Expression { return binary("%", a, b) }
+
+func Eq(a, b Expression) Expression { return compare("==", a, b) }
+func Ne(a, b Expression) Expression { return compare("!=", a, b) }
+func Gt(a, b Expression) Expression { return compare(">", a, b) }
+func Ge(a, b Expression) Expression { return compare(">=", a, b) }
+func Lt(a, b Expression) Expression { return compare("<", a, b) }
+func Le(a, b Expression) Expression { return compare("<=", a, b) }
+
+// And is a && b && ...
+func And(exps ...Expression) Expression { return logic("&&", exps) }
+
+// Or is a || b || ...
+func Or(exps ...Expression) Expression { return logic("||", exps) }
+
+// Not is !exp.
+func Not(exp Expression) Expression {
+	return &UnaryExp{Op: "!", Exp: paren(exp, precOf["!"])}
+}
+
+// Cond is cond ? t : f.
+func Cond(cond, t, f Expression) Expression {
+	return &TernaryNode{
+		Condition:   paren(cond, precOf["?"]+1),
+		BranchTrue:  t,
+		BranchFalse: f,
+	}
+}
+
+// In is `exp in ranges` of the extended syntax. It panics if ranges is empty,
+// or a range is reversed or negative.
+func In(exp Expression, ranges ...Range) Expression {
+	checkRanges(ranges)
+	return &InNode{Exp: paren(exp, precOf["in"]), Ranges: ranges}
+}
+
+// NotIn is `exp not in ranges` of the extended syntax, it panics like In.
+func NotIn(exp Expression, ranges ...Range) Expression {
+	checkRanges(ranges)
+	return &InNode{Exp: paren(exp, precOf["in"]), Not: true, Ranges: ranges}
+}
+
+// checkRanges 检查区间能否按扩展语法打印: 数字字面量不能为负
+func checkRanges(ranges []Range) {
+	if len(ranges) == 0 {
+		panic("plurals: in needs at least one range")
+	}
+	for _, r := range ranges {
+		if r.From < 0 || r.To < r.From {
+			panic(fmt.Sprintf("plurals: invalid range %d..%d", r.From, r.To))
+		}
+	}
+}
+
+// paren 在 exp 的优先级低于 prec 时加上括号
+func paren(exp Expression, prec int) Expression {
+	if precedence(exp) < prec {
+		return &PrimaryNode{Type: TokenTypeLPA, Exp: exp}
+	}
+	return exp
+}
+
+func binary(op string, a, b Expression) Expression {
+	prec := precOf[op]
+	b = paren(b, prec+1)
+	// a + b + c 与解析结果一样放在同一个节点
+	if left, ok := a.(*BinaryNExp); ok && len(left.Op) > 0 && precOf[left.Op[0]] == prec {
+		return &BinaryNExp{
+			Exp:   left.Exp,
+			Op:    append(left.Op[:len(left.Op):len(left.Op)], op),
+			Other: append(left.Other[:len(left.Other):len(left.Other)], b),
+		}
+	}
+	return &BinaryNExp{Exp: paren(a, prec), Op: []string{op}, Other: []Expression{b}}
+}
+
+func compare(op string, a, b Expression) Expression {
+	prec := precOf[op]
+	return &CompareNode{Exp: paren(a, prec), Op: op, Other: paren(b, prec+1)}
+}
+
+func logic(op string, exps []Expression) Expression {
+	switch len(exps) {
+	case 0:
+		// 空的 && 为真, 空的 || 为假
+		return num(b2i(op == "&&"))
+	case 1:
+		return exps[0]
+	}
+	prec := precOf[op]
+	list := make([]Expression, len(exps))
+	for i, exp := range exps {
+		list[i] = paren(exp, prec+1)
+	}
+	return &LogicNode{Op: op, Exps: list}
+}
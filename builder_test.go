@@ -0,0 +1,124 @@
+package plurals
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	n := N()
+	mod10, mod100 := Mod(n, Num(10)), Mod(n, Num(100))
+	for _, tt := range []struct {
+		exp  Expression
+		want string
+	}{
+		{exp: Ne(n, Num(1)), want: "n != 1"},
+		{exp: Cond(And(Eq(mod10, Num(1)), Ne(mod100, Num(11))), Num(0), Cond(Ne(n, Num(0)), Num(1), Num(2))),
+			want: "n % 10 == 1 && n % 100 != 11 ? 0 : n != 0 ? 1 : 2"},
+		{exp: Cond(Eq(n, Num(1)), Num(0), Cond(Or(Eq(n, Num(0)), And(Gt(mod100, Num(0)), Lt(mod100, Num(20)))), Num(1), Num(2))),
+			want: "n == 1 ? 0 : n == 0 || n % 100 > 0 && n % 100 < 20 ? 1 : 2"},
+		{exp: Cond(And(Ge(mod10, Num(2)), Le(mod10, Num(4)), Or(Lt(mod100, Num(10)), Ge(mod100, Num(20)))), Num(1), Num(2)),
+			want: "n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2"},
+		{exp: Mul(Add(n, Num(1)), Num(2)), want: "( n + 1 ) * 2"},
+		{exp: Add(Add(n, Num(1)), Num(2)), want: "n + 1 + 2"},
+		{exp: Sub(n, Sub(Num(1), Num(2))), want: "n - ( 1 - 2 )"},
+		{exp: Not(Eq(n, Num(1))), want: "!( n == 1 )"},
+		{exp: Not(Not(n)), want: "!!n"},
+		{exp: Eq(Eq(n, Num(1)), Num(0)), want: "n == 1 == 0"},
+		{exp: Eq(Num(0), Eq(n, Num(1))), want: "0 == ( n == 1 )"},
+		{exp: Cond(Cond(n, Num(1), Num(0)), Num(2), Num(3)), want: "( n ? 1 : 0 ) ? 2 : 3"},
+		{exp: Num(-5), want: "( 0 - 5 )"},
+		{exp: Num(math.MinInt64), want: "( ( 0 - 9223372036854775807 ) - 1 )"},
+		{exp: And(), want: "1"},
+		{exp: Or(Eq(Var("m"), Num(1)), In(mod10, Range{2, 4}, Range{7, 7})), want: "m == 1 || n % 10 in 2..4, 7"},
+	} {
+		if got := fmt.Sprintf("%v", tt.exp); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+		checkBuilt(t, tt.exp)
+	}
+}
+
+// checkBuilt 检查打印后重新编译的表达式与构建的表达式等价
+func checkBuilt(t *testing.T, built Expression) {
+	t.Helper()
+	s := fmt.Sprintf("%v", built)
+	exp, err := Compile(s, Extended())
+	if err != nil {
+		t.Errorf("compile %q: %+v", s, err)
+		return
+	}
+	for n := range int64(120) {
+		for _, env := range []Vars{{"n": n, "m": 1}, {"n": -n, "m": 2}} {
+			want, werr := EvalEnv(built, env)
+			got, err := EvalEnv(exp, env)
+			if got != want || (err == nil) != (werr == nil) {
+				t.Errorf("%q %v: got=%d, err=%v, want=%d, err=%v", s, env, got, err, want, werr)
+				return
+			}
+		}
+	}
+}
+
+func TestBuilderRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	binaries := []func(a, b Expression) Expression{Add, Sub, Mul, Div, Mod, Eq, Ne, Gt, Ge, Lt, Le,
+		func(a, b Expression) Expression { return And(a, b) },
+		func(a, b Expression) Expression { return Or(a, b) },
+	}
+	var gen func(depth int) Expression
+	gen = func(depth int) Expression {
+		if depth == 0 || r.Intn(4) == 0 {
+			if r.Intn(2) == 0 {
+				return N()
+			}
+			return Num(r.Int63n(30) - 5)
+		}
+		switch r.Intn(6) {
+		case 0:
+			return Not(gen(depth - 1))
+		case 1:
+			return Cond(gen(depth-1), gen(depth-1), gen(depth-1))
+		case 2:
+			ranges := make([]Range, 1+r.Intn(3))
+			for i := range ranges {
+				from := r.Int63n(20)
+				ranges[i] = Range{From: from, To: from + r.Int63n(5)}
+			}
+			if r.Intn(2) == 0 {
+				return NotIn(gen(depth-1), ranges...)
+			}
+			return In(gen(depth-1), ranges...)
+		}
+		return binaries[r.Intn(len(binaries))](gen(depth-1), gen(depth-1))
+	}
+	for range 500 {
+		checkBuilt(t, gen(5))
+	}
+}
+
+func TestBuilderPanics(t *testing.T) {
+	for name, build := range map[string]func() Expression{
+		"empty in":    func() Expression { return In(N()) },
+		"negative":    func() Expression { return In(N(), Range{-1, 3}) },
+		"reversed":    func() Expression { return NotIn(N(), Range{4, 2}) },
+		"keyword":     func() Expression { return Var("in") },
+		"not":         func() Expression { return Var("not") },
+		"digit":       func() Expression { return Var("1x") },
+		"empty name":  func() Expression { return Var("") },
+		"uppercase":   func() Expression { return Var("N") },
+		"punctuation": func() Expression { return Var("a-b") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: want panic", name)
+				}
+			}()
+			build()
+		}()
+	}
+	checkBuilt(t, Add(Var("x_1"), Var("_m")))
+}
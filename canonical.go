@@ -0,0 +1,265 @@
+package plurals
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Canonicalize returns the normal form of exp, an equivalent expression that
+// is the same for rules written with different parentheses, operand order
+// or syntax:
+//   - redundant parentheses and wrapper nodes are removed, `in` is rewritten
+//     in standard syntax, and nested && or || are flattened;
+//   - constant sub expressions are folded, constants are moved to the right of
+//     comparisons and compared with >= or <= only (`1 < n` is `n >= 2`),
+//     ! is pushed into comparisons (`!(a && b == 1)` is `!a || b != 1`), and
+//     `c != d ? a : b` is written as `c == d ? b : a`;
+//   - operands of &&, ||, == and != are sorted, unless evaluating them could
+//     fail, so that the same n still gives the same error.
+func Canonicalize(exp Expression) Expression {
+	return canon(Standard(exp))
+}
+
+// Canonical returns the String() of the normal form of exp, two rules with the
+// same Canonical string select the same form for every n.
+func Canonical(exp Expression) string {
+	return fmt.Sprintf("%v", Canonicalize(exp))
+}
+
+// CanonicalHash returns a stable 64-bit FNV-1a hash of Canonical(exp).
+func CanonicalHash(exp Expression) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(Canonical(exp)))
+	return h.Sum64()
+}
+
+// canon 自底向上规范化, 每个节点在操作数折叠后只折叠一次
+func canon(exp Expression) Expression {
+	return fold(canonNode(unwrap(exp)))
+}
+
+func canonNode(exp Expression) Expression {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		cond, t, f := canon(e.Condition), canon(e.BranchTrue), canon(e.BranchFalse)
+		if c, ok := cond.(*PrimaryNode); ok && c.Type == TokenTypeNUM {
+			// 条件为常量时只会计算其中一个分支
+			if i2b(c.Num) {
+				return t
+			}
+			return f
+		}
+		// 去掉条件中所有的 !, 如 !!n ? a : b 即 n ? a : b
+		for {
+			c, ok := cond.(*UnaryExp)
+			if !ok {
+				break
+			}
+			cond, t, f = unwrap(c.Exp), f, t
+		}
+		if c, ok := cond.(*CompareNode); ok && c.Op == "!=" {
+			cond, t, f = canonCompare("==", c.Exp, c.Other), f, t
+		}
+		if fmt.Sprint(t) == fmt.Sprint(f) && !mayFail(cond) {
+			return t
+		}
+		return Cond(cond, t, f)
+	case *LogicNode:
+		return canonLogic(e.Op, mapList(e.Exps, canon))
+	case *CompareNode:
+		return canonCompare(e.Op, canon(e.Exp), canon(e.Other))
+	case *BinaryNExp:
+		node := canon(e.Exp)
+		for i, other := range e.Other {
+			node = binary(e.Op[i], node, canon(other))
+		}
+		return node
+	case *UnaryExp:
+		x := canon(e.Exp)
+		if neg, ok := negation(x); ok {
+			return neg
+		}
+		return Not(x)
+	case *PrimaryNode:
+		if e.Type == TokenTypeIDN {
+			return &PrimaryNode{Type: TokenTypeIDN, Name: e.Name}
+		}
+		if e.Big != nil {
+			return &PrimaryNode{Type: TokenTypeNUM, Num: e.Num, Big: e.Big}
+		}
+		return num(e.Num)
+	}
+	return exp
+}
+
+// canonLogic 的操作数已是规范形式
+func canonLogic(op string, exps []Expression) Expression {
+	var list []Expression
+	for _, sub := range exps {
+		// a && (b && c) 即 a && b && c
+		if l, ok := sub.(*LogicNode); ok && l.Op == op {
+			list = append(list, mapList(l.Exps, unwrap)...)
+			continue
+		}
+		list = append(list, sub)
+	}
+	if !slices.ContainsFunc(list, mayFail) {
+		sortExps(list)
+	}
+	return logic(op, list)
+}
+
+// canonCompare 的操作数已是规范形式: 常量放在右侧, 与常量比较时只用 >= <=
+func canonCompare(op string, a, b Expression) Expression {
+	a, b = unwrap(a), unwrap(b)
+	if !hasVar(a) && hasVar(b) {
+		a, b, op = b, a, flip[op]
+	}
+	if (op == "==" || op == "!=") && hasVar(b) && !mayFail(a) && !mayFail(b) {
+		list := []Expression{a, b}
+		sortExps(list)
+		a, b = list[0], list[1]
+	}
+	if c, ok := b.(*PrimaryNode); ok && c.Type == TokenTypeNUM {
+		switch {
+		case op == ">" && c.Num < math.MaxInt64:
+			op, b = ">=", num(c.Num+1)
+		case op == "<" && c.Num > 0:
+			op, b = "<=", num(c.Num-1)
+		}
+	}
+	return compare(op, a, b)
+}
+
+// negation returns the normal form of !x without a ! operator, if there is one.
+func negation(x Expression) (Expression, bool) {
+	switch x := x.(type) {
+	case *CompareNode:
+		return canonCompare(negate[x.Op], x.Exp, x.Other), true
+	case *LogicNode:
+		// !(a && b) 即 !a || !b
+		list := make([]Expression, len(x.Exps))
+		for i, sub := range x.Exps {
+			neg, ok := negation(unwrap(sub))
+			if !ok {
+				return nil, false
+			}
+			list[i] = neg
+		}
+		return canonLogic(dual[x.Op], list), true
+	case *UnaryExp:
+		// !!x 在 x 为布尔值时即 x
+		if inner := unwrap(x.Exp); isBool(inner) {
+			return inner, true
+		}
+	}
+	return nil, false
+}
+
+var (
+	flip   = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+	negate = map[string]string{"==": "!=", "!=": "==", "<": ">=", "<=": ">", ">": "<=", ">=": "<"}
+	dual   = map[string]string{"&&": "||", "||": "&&"}
+)
+
+func hasVar(exp Expression) bool {
+	return !inspect(exp, func(e Expression) bool {
+		p, ok := e.(*PrimaryNode)
+		return !ok || p.Type != TokenTypeIDN
+	})
+}
+
+// fold replaces exp by its value when every operand is a number that is
+// already folded, see foldable.
+func fold(exp Expression) Expression {
+	if p, ok := exp.(*PrimaryNode); ok && p.Type != TokenTypeLPA {
+		return exp
+	}
+	subs := children(exp)
+	if len(subs) == 0 {
+		return exp
+	}
+	for _, sub := range subs {
+		if !isNum(unwrap(sub)) {
+			return exp
+		}
+	}
+	if v, ok := foldable(exp); ok {
+		return num(v)
+	}
+	return exp
+}
+
+// foldable evaluates exp if it is a non-negative constant with the same value
+// in every domain: GNU gettext uses unsigned numbers, and EvalBig does not
+// overflow.
+func foldable(exp Expression) (int64, bool) {
+	v, err := exp.Eval(0)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	exact, err := EvalBig(exp, new(big.Int))
+	if err != nil || !exact.IsInt64() || exact.Int64() != v {
+		return 0, false
+	}
+	if gnu, err := EvalGNU(exp, 0); err != nil || gnu != uint64(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+func isNum(exp Expression) bool {
+	p, ok := exp.(*PrimaryNode)
+	return ok && p.Type == TokenTypeNUM
+}
+
+// isBool 表达式的值只可能是 0 或 1
+func isBool(exp Expression) bool {
+	switch e := exp.(type) {
+	case *CompareNode:
+		return e.Other != nil
+	case *LogicNode:
+		return len(e.Exps) > 1
+	case *UnaryExp:
+		return e.Op == "!"
+	case *InNode:
+		return true
+	}
+	return false
+}
+
+// mayFail reports whether evaluating exp could return an error: a variable
+// other than n, or a division by something other than a non-zero constant.
+func mayFail(exp Expression) bool {
+	return !inspect(exp, func(e Expression) bool {
+		switch e := e.(type) {
+		case *PrimaryNode:
+			return e.Type != TokenTypeIDN || e.Name == ""
+		case *BinaryNExp:
+			for i, op := range e.Op {
+				if op != "/" && op != "%" {
+					continue
+				}
+				if d, ok := unwrap(e.Other[i]).(*PrimaryNode); !ok || d.Type != TokenTypeNUM || d.Num == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+// sortExps sorts list by String().
+func sortExps(list []Expression) {
+	keys := make(map[Expression]string, len(list))
+	for _, exp := range list {
+		keys[exp] = fmt.Sprint(exp)
+	}
+	slices.SortStableFunc(list, func(a, b Expression) int {
+		return strings.Compare(keys[a], keys[b])
+	})
+}
@@ -0,0 +1,124 @@
+package plurals
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	for _, tt := range []struct {
+		exp  []string // 规范形式相同的写法
+		want string
+	}{
+		{exp: []string{"n != 1", "(n != 1)", "((n)!=(1))", "1 != n", "!(n == 1)", "!!(n != 1)"}, want: "n != 1"},
+		{exp: []string{"n > 1", "1 < n", "!(n <= 1)", "n > 2 - 1", "n > (3 * 4 + 1) / 13"}, want: "n >= 2"},
+		{exp: []string{"n == 1 ? 0 : 1", "n != 1 ? 1 : 0", "!(n != 1) ? 0 : 1", "(1 == n) ? 0 : 1"}, want: "n == 1 ? 0 : 1"},
+		{exp: []string{
+			"n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2",
+			"(n%100!=11 && n%10==1) ? 0 : ((n%10>=2 && n%10<=4) && (n%100>=20 || n%100<10)) ? 1 : 2",
+			"n%10 in 1 && n%100 not in 11 ? 0 : n%10 in 2..4 && n%100 not in 10..19 ? 1 : 2",
+		}, want: "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 <= 4 && n % 10 >= 2 && ( n % 100 <= 9 || n % 100 >= 20 ) ? 1 : 2"},
+		{exp: []string{"n == 1 || n == 2 || n == 3", "n == 3 || (n == 2 || n == 1)"}, want: "n == 1 || n == 2 || n == 3"},
+		{exp: []string{"1 ? n : 2", "0 ? 2 : n", "n == 0 ? n : n"}, want: "n"},
+		{exp: []string{"n % 10 == n % 100", "n % 100 == n % 10"}, want: "n % 10 == n % 100"},
+		// 可能出错时保持计算顺序
+		{exp: []string{"n == 0 || 10 / n == 1"}, want: "n == 0 || 10 / n == 1"},
+		{exp: []string{"n == 0 ? 1 / 0 : 1 / 0", "1 / 0"}, want: "1 / 0"},
+		{exp: []string{"n > m && m > 1"}, want: "n > m && m >= 2"},
+		// 负数与溢出不折叠, 因为 GNU 中是无符号数
+		{exp: []string{"n == (0 - 1)"}, want: "n == 0 - 1"},
+		{exp: []string{"n == 9223372036854775807 * 2"}, want: "n == 9223372036854775807 * 2"},
+		{exp: []string{"!(n % 10)"}, want: "!( n % 10 )"},
+		{exp: []string{"!!(n % 10)"}, want: "!!( n % 10 )"},
+		{exp: []string{"!!n ? 6 : 11", "!n ? 11 : 6", "n ? 6 : 11", "!!!n ? 11 : 6"}, want: "n ? 6 : 11"},
+	} {
+		for _, s := range tt.exp {
+			exp, err := Compile(s, Extended())
+			if err != nil {
+				t.Fatalf("compile %q: %+v", s, err)
+			}
+			if got := Canonical(exp); got != tt.want {
+				t.Errorf("Canonical(%q)=%q, want %q", s, got, tt.want)
+			}
+			checkCanonical(t, s, exp)
+		}
+	}
+}
+
+func TestCanonicalConformance(t *testing.T) {
+	for _, tt := range cConformance {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.exp, err)
+		}
+		checkCanonical(t, tt.exp, exp)
+	}
+	for _, r := range commonRules {
+		exp, err := Compile(r.exp)
+		if err != nil {
+			t.Fatalf("compile %q: %+v", r.exp, err)
+		}
+		checkCanonical(t, r.exp, exp)
+	}
+}
+
+// checkCanonical 检查规范形式与原表达式等价, 且再次规范化结果不变
+func checkCanonical(t *testing.T, s string, exp Expression) {
+	t.Helper()
+	c := Canonicalize(exp)
+	for n := int64(-20); n <= 200; n++ {
+		want, wantErr := EvalEnv(exp, Vars{"n": n, "m": 3})
+		got, err := EvalEnv(c, Vars{"n": n, "m": 3})
+		if got != want || (err == nil) != (wantErr == nil) {
+			t.Errorf("%q n=%d: canonical %v got %d, %v, want %d, %v", s, n, c, got, err, want, wantErr)
+			return
+		}
+	}
+	again, err := Compile(Canonical(exp), Extended())
+	if err != nil {
+		t.Errorf("%q: compile canonical %q: %+v", s, Canonical(exp), err)
+		return
+	}
+	if Canonical(again) != Canonical(exp) {
+		t.Errorf("%q: Canonical is not stable: %q, then %q", s, Canonical(exp), Canonical(again))
+	}
+	if CanonicalHash(again) != CanonicalHash(exp) {
+		t.Errorf("%q: CanonicalHash is not stable", s)
+	}
+}
+
+func TestCompileCacheCanonical(t *testing.T) {
+	a, err := NewRule("n%10==1 && n%100!=11 ? 0 : 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRule("(n % 100 != 11) && (1 == n % 10) ? 0 : 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个规则保留自己的语法树, 只共用按规范形式查找的原生实现
+	if got, want := fmt.Sprint(b.Expression()), "( n % 100 != 11 ) && ( 1 == n % 10 ) ? 0 : 1"; got != want {
+		t.Errorf("b.Expression()=%q, want %q", got, want)
+	}
+	if got, want := fmt.Sprint(a.Expression()), "n % 10 == 1 && n % 100 != 11 ? 0 : 1"; got != want {
+		t.Errorf("a.Expression()=%q, want %q", got, want)
+	}
+	mu.RLock()
+	_, ok := canons[Canonical(a.Expression())]
+	mu.RUnlock()
+	if !ok || a.eval != b.eval {
+		t.Errorf("equivalent rules should share the canonical evaluation tree")
+	}
+	// 可能出错的规则用自己的语法树求值, 错误中的位置与各自的输入对应
+	c, err := NewRule("n == 0 || 10 / n == 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewRule("n==0 || 10/n==1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.eval != c.exp || d.eval != d.exp {
+		t.Errorf("rules that may fail should evaluate their own expression")
+	}
+}
@@ -0,0 +1,307 @@
+package plurals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of Syntax nodes.
+const (
+	SyntaxPlural  = "Plural"  // 整个输入, 包括首尾空白与分号
+	SyntaxTernary = "Ternary" // a ? b : c
+	SyntaxLogic   = "Logic"   // a && b && c, a || b
+	SyntaxCompare = "Compare" // a == b, a < b
+	SyntaxIn      = "In"      // a in 1..2, 5	扩展语法
+	SyntaxBinary  = "Binary"  // a + b - c, a * b
+	SyntaxUnary   = "Unary"   // !a
+	SyntaxParen   = "Paren"   // ( a )
+	SyntaxNum     = "Num"     // 1
+	SyntaxVar     = "Var"     // n
+	SyntaxToken   = "Token"   // 运算符、括号、分号与空白
+)
+
+// Syntax is a node of the concrete syntax tree of an expression. Unlike
+// Expression it keeps every token, whitespace run and redundant parenthesis:
+// String() prints the source back byte for byte.
+//
+// Num, Var and Token nodes are leaves holding a Token, the Start and End of
+// which refer to the source they were parsed from. Other nodes hold their
+// tokens and sub expressions as Children, in source order.
+type Syntax struct {
+	Kind     string
+	Token    Token
+	Children []*Syntax
+	opts     CompileOptions
+	slot     int // 在父节点中不加括号时允许的最低优先级
+}
+
+// ParseSyntax parses s into a concrete syntax tree of kind SyntaxPlural.
+func ParseSyntax(s string, opts ...Option) (*Syntax, error) {
+	o := DefaultCompileOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.parseSyntax(s)
+}
+
+func (o CompileOptions) parseSyntax(s string) (*Syntax, error) {
+	exp, err := o.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	// 语法已检查过, 此处只需带空白的 token
+	lex := o
+	lex.Whitespace, lex.OnWarning, lex.MaxTokens = true, nil, 0
+	tokens, err := lex.Lex(s)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		// not in 的 Value 不含中间的空白
+		tokens[i].Value = s[tokens[i].Start:tokens[i].End]
+	}
+	b := &syntaxBuilder{tokens: tokens, opts: o}
+	root := b.node(SyntaxPlural, nil)
+	b.tokensUntil(root, SpanOf(exp).Start)
+	root.Children = append(root.Children, b.build(exp, 0))
+	b.tokensUntil(root, len(s))
+	return root, nil
+}
+
+type syntaxBuilder struct {
+	tokens []Token
+	i      int // 下一个未使用的 token
+	opts   CompileOptions
+}
+
+func (b *syntaxBuilder) node(kind string, token *Token) *Syntax {
+	n := &Syntax{Kind: kind, opts: b.opts}
+	if token != nil {
+		n.Token = *token
+	}
+	return n
+}
+
+// tokensUntil 把 end 之前剩余的 token 加到 parent 中
+func (b *syntaxBuilder) tokensUntil(parent *Syntax, end int) {
+	for ; b.i < len(b.tokens) && b.tokens[b.i].Start < end; b.i++ {
+		parent.Children = append(parent.Children, b.node(SyntaxToken, &b.tokens[b.i]))
+	}
+}
+
+func (b *syntaxBuilder) build(exp Expression, slot int) *Syntax {
+	exp = unwrapNode(exp)
+	kind := syntaxKind(exp)
+	if kind == SyntaxNum || kind == SyntaxVar {
+		token := &b.tokens[b.i]
+		b.i++
+		n := b.node(kind, token)
+		n.slot = slot
+		return n
+	}
+	n := b.node(kind, nil)
+	n.slot = slot
+	for i, sub := range children(exp) {
+		sub = unwrapNode(sub)
+		b.tokensUntil(n, SpanOf(sub).Start)
+		n.Children = append(n.Children, b.build(sub, slotOf(exp, i)))
+	}
+	b.tokensUntil(n, SpanOf(exp).End)
+	return n
+}
+
+// slotOf 返回 exp 的第 i 个操作数不加括号时允许的最低优先级, 与构建函数一致
+func slotOf(exp Expression, i int) int {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		if i == 0 {
+			return precOf["?"] + 1
+		}
+	case *LogicNode:
+		return precOf[e.Op] + 1
+	case *CompareNode:
+		if i > 0 {
+			return precOf[e.Op] + 1
+		}
+		return precOf[e.Op]
+	case *BinaryNExp:
+		if i > 0 {
+			return precOf[e.Op[i-1]] + 1
+		}
+		return precOf[e.Op[0]]
+	case *UnaryExp:
+		return precOf[e.Op]
+	case *InNode:
+		return precOf["in"]
+	}
+	return 0
+}
+
+// unwrapNode 与 unwrap 相同, 但保留括号
+func unwrapNode(exp Expression) Expression {
+	for {
+		switch e := exp.(type) {
+		case *LogicNode:
+			if len(e.Exps) != 1 {
+				return exp
+			}
+			exp = e.Exps[0]
+		case *CompareNode:
+			if e.Other != nil {
+				return exp
+			}
+			exp = e.Exp
+		case *BinaryNExp:
+			if len(e.Other) != 0 {
+				return exp
+			}
+			exp = e.Exp
+		case *UnaryExp:
+			if e.Op == "!" {
+				return exp
+			}
+			exp = e.Exp
+		default:
+			return exp
+		}
+	}
+}
+
+func syntaxKind(exp Expression) string {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		return SyntaxTernary
+	case *LogicNode:
+		return SyntaxLogic
+	case *CompareNode:
+		return SyntaxCompare
+	case *InNode:
+		return SyntaxIn
+	case *BinaryNExp:
+		return SyntaxBinary
+	case *UnaryExp:
+		return SyntaxUnary
+	case *PrimaryNode:
+		switch e.Type {
+		case TokenTypeLPA:
+			return SyntaxParen
+		case TokenTypeNUM:
+			return SyntaxNum
+		}
+		return SyntaxVar
+	}
+	return SyntaxToken
+}
+
+func (s *Syntax) String() string {
+	var sb strings.Builder
+	s.Walk(func(n *Syntax) bool {
+		if n.Children == nil {
+			sb.WriteString(n.Token.Value)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// Expression compiles the current source of s, with the options s was parsed with.
+func (s *Syntax) Expression() (Expression, error) {
+	return s.opts.Compile(s.String())
+}
+
+// Walk calls f for s and its descendants in source order,
+// the children of a node are skipped when f returns false.
+func (s *Syntax) Walk(f func(*Syntax) bool) {
+	if !f(s) {
+		return
+	}
+	for _, child := range s.Children {
+		child.Walk(f)
+	}
+}
+
+// Find returns the nodes of the given kind in source order.
+func (s *Syntax) Find(kind string) (nodes []*Syntax) {
+	s.Walk(func(n *Syntax) bool {
+		if n.Kind == kind {
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	return
+}
+
+// Operator returns the operator of s, such as `%` of `n % 10` or `?` of a
+// ternary expression, or "" if s has none.
+func (s *Syntax) Operator() string {
+	for _, child := range s.Children {
+		if child.Kind == SyntaxToken && child.Token.Type != TokenTypeWSP &&
+			child.Token.Type != TokenTypeLPA && child.Token.Type != TokenTypeRPA {
+			return child.Token.Value
+		}
+	}
+	return ""
+}
+
+// Operands returns the sub expressions of s, without tokens.
+func (s *Syntax) Operands() (operands []*Syntax) {
+	for _, child := range s.Children {
+		if child.Kind != SyntaxToken {
+			operands = append(operands, child)
+		}
+	}
+	return
+}
+
+// Replace replaces the expression s in place with src, keeping the whitespace
+// around s. src is parenthesized only if its precedence is too low for the
+// place of s in its parent, so the rest of the tree keeps its meaning.
+func (s *Syntax) Replace(src string) error {
+	if s.Kind == SyntaxPlural || s.Kind == SyntaxToken {
+		return fmt.Errorf("cannot replace a %s node", s.Kind)
+	}
+	root, err := s.opts.parseSyntax(src)
+	if err != nil {
+		return err
+	}
+	n := root.Operands()[0]
+	exp, err := n.Expression()
+	if err != nil {
+		return err
+	}
+	if precedence(exp) < s.slot {
+		n = &Syntax{Kind: SyntaxParen, opts: s.opts, Children: []*Syntax{
+			{Kind: SyntaxToken, Token: Token{Type: TokenTypeLPA, Value: "("}, opts: s.opts},
+			n,
+			{Kind: SyntaxToken, Token: Token{Type: TokenTypeRPA, Value: ")"}, opts: s.opts},
+		}}
+	}
+	n.slot = s.slot
+	*s = *n
+	return nil
+}
+
+// Swap exchanges the expressions a and b, e.g. two branches of a ternary
+// expression. Neither may contain the other.
+func Swap(a, b *Syntax) error {
+	if a.contains(b) || b.contains(a) {
+		return fmt.Errorf("cannot swap %q and %q: one contains the other", a, b)
+	}
+	oldA, srcA, srcB := *a, a.String(), b.String()
+	if err := a.Replace(srcB); err != nil {
+		return err
+	}
+	if err := b.Replace(srcA); err != nil {
+		*a = oldA
+		return err
+	}
+	return nil
+}
+
+func (s *Syntax) contains(other *Syntax) (found bool) {
+	s.Walk(func(n *Syntax) bool {
+		found = found || n == other
+		return !found
+	})
+	return
+}
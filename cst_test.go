@@ -0,0 +1,110 @@
+package plurals
+
+import "testing"
+
+func TestSyntaxRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		opts []Option
+	}{
+		{src: "n != 1"},
+		{src: "  (n%10==1 && n%100!=11) ? 0 :\t( ( n % 10 >= 2 ) ) ? 1 : 2 ; "},
+		{src: "!!n+1-2*(3/n);"},
+		{src: "n % 10 in 2..4 , 7 && m not  in 12..14 ? 1 : 2", opts: []Option{Extended()}},
+		{src: "(N != 1UL)\\\n ? 1 : 0", opts: []Option{Lenient()}},
+	} {
+		cst, err := ParseSyntax(tt.src, tt.opts...)
+		if err != nil {
+			t.Fatalf("parse %q: %+v", tt.src, err)
+		}
+		if got := cst.String(); got != tt.src {
+			t.Errorf("got %q, want %q", got, tt.src)
+		}
+		got, err := cst.Expression()
+		if err != nil {
+			t.Fatalf("%q: %+v", tt.src, err)
+		}
+		if want, _ := Compile(tt.src, tt.opts...); !Equal(got, want) {
+			t.Errorf("%q: Expression()=%v, want %v", tt.src, got, want)
+		}
+		// 每个节点都能单独编译
+		cst.Walk(func(n *Syntax) bool {
+			if n.Kind == SyntaxToken || n.Kind == SyntaxPlural {
+				return true
+			}
+			if _, err := n.Expression(); err != nil {
+				t.Errorf("%q: node %s %q: %+v", tt.src, n.Kind, n, err)
+			}
+			return true
+		})
+	}
+}
+
+func TestSyntaxEdit(t *testing.T) {
+	cst, err := ParseSyntax("n%10==1 && n%100!=11 ? 0 : (n%10>=2 && n%10<=4) ? 1 : 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 把 n%100 改为 n%1000
+	for _, bin := range cst.Find(SyntaxBinary) {
+		if ops := bin.Operands(); bin.Operator() == "%" && ops[1].String() == "100" {
+			if err := ops[1].Replace("1000"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if want := "n%10==1 && n%1000!=11 ? 0 : (n%10>=2 && n%10<=4) ? 1 : 2"; cst.String() != want {
+		t.Errorf("got %q, want %q", cst, want)
+	}
+	// 交换分支
+	ternary := cst.Find(SyntaxTernary)[0]
+	ops := ternary.Operands()
+	if err := Swap(ternary, ops[0]); err == nil {
+		t.Errorf("swap should fail")
+	}
+	if err := Swap(ops[1], ops[2]); err != nil {
+		t.Fatal(err)
+	}
+	// 分支中的三元表达式不需要括号
+	if want := "n%10==1 && n%1000!=11 ? (n%10>=2 && n%10<=4) ? 1 : 2 : 0"; cst.String() != want {
+		t.Errorf("got %q, want %q", cst, want)
+	}
+	// 只在优先级对所在位置太低时加括号
+	mod := cst.Find(SyntaxBinary)[0]
+	if err := mod.Replace("n + 1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "n + 1==1 && n%1000!=11 ? (n%10>=2 && n%10<=4) ? 1 : 2 : 0"; cst.String() != want {
+		t.Errorf("got %q, want %q", cst, want)
+	}
+	if err := mod.Replace("n || 1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "(n || 1)==1 && n%1000!=11 ? (n%10>=2 && n%10<=4) ? 1 : 2 : 0"; cst.String() != want {
+		t.Errorf("got %q, want %q", cst, want)
+	}
+	// 右侧操作数与左结合的运算符同级时也要加括号
+	sub := cst.Find(SyntaxCompare)[1].Operands()[1]
+	if err := sub.Replace("10 - 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.Find(SyntaxCompare)[1].Operands()[1].Operands()[1].Replace("2 + 3"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "(n || 1)==1 && n%1000!=10 - (2 + 3) ? (n%10>=2 && n%10<=4) ? 1 : 2 : 0"; cst.String() != want {
+		t.Errorf("got %q, want %q", cst, want)
+	}
+	exp, err := cst.Expression()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := exp.Eval(0); got != 2 {
+		t.Errorf("got %d, want 2", got)
+	}
+	if err := cst.Replace("n"); err == nil {
+		t.Errorf("replace root should fail")
+	}
+	if err := mod.Replace("n +"); err == nil {
+		t.Errorf("replace with invalid source should fail")
+	}
+}
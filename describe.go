@@ -0,0 +1,298 @@
+package plurals
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+)
+
+// maxDescribe bounds hi + period of the rules Describe enumerates.
+const maxDescribe = 1 << 20
+
+// examples is the number of examples Describe finds for each form.
+const examples = 6
+
+// FormInfo describes which numbers select a form.
+type FormInfo struct {
+	Form        int64
+	Description string  // e.g. "numbers ending in 1, except those ending in 11"
+	Examples    []int64 // 选择该形式的最小的几个数
+}
+
+// Describe explains in words which non-negative n select each form of exp,
+// in order of the form index. Examples lists the first 6 n of each form,
+// fewer only when fewer n select it. Like EvalRange it needs exp to use n
+// only as `n % c` or in comparisons with constants.
+func Describe(exp Expression) ([]FormInfo, error) {
+	s, ok := analyze(exp)
+	if !ok {
+		return nil, fmt.Errorf("cannot describe %v: n is not only used as n %% c or compared with constants", exp)
+	}
+	hi, period := max(s.hi, 0), s.period
+	if hi > maxDescribe-period {
+		return nil, fmt.Errorf("cannot describe %v: too many numbers to enumerate", exp)
+	}
+	// n < hi 单独计算, 更大的 n 只与 n % period 有关
+	small := make([]int64, hi)
+	for n := range hi {
+		val, err := exp.Eval(n)
+		if err != nil {
+			return nil, fmt.Errorf("n=%d: %w", n, err)
+		}
+		small[n] = val
+	}
+	pattern := make([]int64, period)
+	for n := hi; n < hi+period; n++ {
+		val, err := exp.Eval(n)
+		if err != nil {
+			return nil, fmt.Errorf("n=%d: %w", n, err)
+		}
+		pattern[n%period] = val
+	}
+	forms := slices.Sorted(slices.Values(slices.Concat(small, pattern)))
+	forms = slices.Compact(forms)
+
+	infos := make([]FormInfo, len(forms))
+	for i, form := range forms {
+		in := make([]bool, period)
+		for r, val := range pattern {
+			in[r] = val == form
+		}
+		var plus, minus []int64 // 与周期规律不同的小数字
+		for n, val := range small {
+			switch {
+			case val == form && !in[int64(n)%period]:
+				plus = append(plus, int64(n))
+			case val != form && in[int64(n)%period]:
+				minus = append(minus, int64(n))
+			}
+		}
+		desc := describeResidues(in)
+		switch {
+		case desc == "":
+			desc = listNumbers(plus, "%d")
+		case len(plus) > 0:
+			desc = listNumbers(plus, "%d") + ", and " + desc
+		}
+		if len(minus) > 0 {
+			desc += " (but not " + listNumbers(minus, "%d") + ")"
+		}
+		infos[i] = FormInfo{Form: form, Description: desc}
+	}
+
+	// 从 0 开始找例子, 每个形式 6 个: 周期中出现的形式每个周期至少出现一次,
+	// 只在 hi 之前出现的形式不足 6 个时全部列出
+	index := make(map[int64]int, len(forms))
+	for i, form := range forms {
+		index[form] = i
+	}
+	want := 0 // 要找的例子总数
+	for _, info := range infos {
+		if slices.Contains(pattern, info.Form) {
+			want += examples
+			continue
+		}
+		count := 0
+		for _, val := range small {
+			if val == info.Form {
+				count++
+			}
+		}
+		want += min(count, examples)
+	}
+	found := 0
+	for n := int64(0); found < want; n++ {
+		val := pattern[n%period]
+		if n < hi {
+			val = small[n]
+		}
+		if info := &infos[index[val]]; len(info.Examples) < examples {
+			info.Examples = append(info.Examples, n)
+			found++
+		}
+	}
+	return infos, nil
+}
+
+// describeResidues 描述 in[n % period] 为真的数
+func describeResidues(in []bool) string {
+	count := 0
+	for _, ok := range in {
+		if ok {
+			count++
+		}
+	}
+	switch count {
+	case 0:
+		return ""
+	case len(in):
+		return "all numbers"
+	}
+	period := int64(len(in))
+	digits := 0
+	for p := period; p > 1 && p%10 == 0; p /= 10 {
+		digits++
+	}
+	if pow10(digits) != period {
+		var rs []int64
+		for r, ok := range in {
+			if ok {
+				rs = append(rs, int64(r))
+			}
+		}
+		return fmt.Sprintf("numbers n with n %% %d = %s", period, listNumbers(rs, "%d"))
+	}
+
+	// 按末尾数字逐位描述: 每一位上与上一位结论不同的类别记为一条
+	type clause struct {
+		digits  int
+		include bool
+		endings []int64
+	}
+	var clauses []clause
+	var decide func(c int64, j int, parent bool)
+	decide = func(c int64, j int, parent bool) {
+		total, hit := period/pow10(j), 0
+		for r := c; r < period; r += pow10(j) {
+			if in[r] {
+				hit++
+			}
+		}
+		d := parent
+		if 2*int64(hit) > total {
+			d = true
+		} else if 2*int64(hit) < total {
+			d = false
+		}
+		if d != parent {
+			i := slices.IndexFunc(clauses, func(cl clause) bool { return cl.digits == j && cl.include == d })
+			if i < 0 {
+				clauses = append(clauses, clause{digits: j, include: d})
+				i = len(clauses) - 1
+			}
+			clauses[i].endings = append(clauses[i].endings, c)
+		}
+		for digit := range int64(10) {
+			if j < digits {
+				decide(c+digit*pow10(j), j+1, d)
+			}
+		}
+	}
+	decide(0, 0, false)
+	slices.SortStableFunc(clauses, func(a, b clause) int { return a.digits - b.digits })
+
+	var sb strings.Builder
+	for i, cl := range clauses {
+		slices.Sort(cl.endings)
+		endings := listNumbers(cl.endings, fmt.Sprintf("%%0%dd", cl.digits))
+		switch {
+		case cl.digits == 0:
+			sb.WriteString("all numbers")
+		case i == 0:
+			sb.WriteString("numbers ending in " + endings)
+		case cl.include == clauses[i-1].include:
+			sb.WriteString(" and those ending in " + endings)
+		case cl.include:
+			sb.WriteString(", but including those ending in " + endings)
+		default:
+			sb.WriteString(", except those ending in " + endings)
+		}
+	}
+	return sb.String()
+}
+
+func pow10(j int) int64 {
+	p := int64(1)
+	for range j {
+		p *= 10
+	}
+	return p
+}
+
+// listNumbers 列出 ns, 连续三个以上的数写作范围: 1, 3-5 or 7
+func listNumbers(ns []int64, format string) string {
+	var items []string
+	for i := 0; i < len(ns); {
+		j := i
+		for j+1 < len(ns) && ns[j+1] == ns[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			items = append(items, fmt.Sprintf(format+"-"+format, ns[i], ns[j]))
+			i = j + 1
+			continue
+		}
+		items = append(items, fmt.Sprintf(format, ns[i]))
+		i++
+	}
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
+
+// Language is a named Plural-Forms rule for a cheat sheet.
+type Language struct {
+	Name string
+	Rule string
+}
+
+// CheatSheetMarkdown renders the forms of each language as Markdown tables.
+func CheatSheetMarkdown(langs []Language) (string, error) {
+	var sb strings.Builder
+	for i, lang := range langs {
+		infos, err := describeLanguage(lang)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n\n`%s`\n\n", lang.Name, lang.Rule)
+		sb.WriteString("| Form | Numbers | Examples |\n|---|---|---|\n")
+		for _, info := range infos {
+			fmt.Fprintf(&sb, "| %d | %s | %s |\n", info.Form, info.Description, joinNumbers(info.Examples))
+		}
+	}
+	return sb.String(), nil
+}
+
+// CheatSheetHTML renders the forms of each language as HTML tables.
+func CheatSheetHTML(langs []Language) (string, error) {
+	var sb strings.Builder
+	for _, lang := range langs {
+		infos, err := describeLanguage(lang)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "<h2>%s</h2>\n<p><code>%s</code></p>\n", html.EscapeString(lang.Name), html.EscapeString(lang.Rule))
+		sb.WriteString("<table>\n<tr><th>Form</th><th>Numbers</th><th>Examples</th></tr>\n")
+		for _, info := range infos {
+			fmt.Fprintf(&sb, "<tr><td>%d</td><td>%s</td><td>%s</td></tr>\n",
+				info.Form, html.EscapeString(info.Description), joinNumbers(info.Examples))
+		}
+		sb.WriteString("</table>\n")
+	}
+	return sb.String(), nil
+}
+
+func describeLanguage(lang Language) ([]FormInfo, error) {
+	exp, err := Compile(lang.Rule, Extended())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", lang.Name, err)
+	}
+	infos, err := Describe(exp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", lang.Name, err)
+	}
+	return infos, nil
+}
+
+func joinNumbers(ns []int64) string {
+	items := make([]string, len(ns))
+	for i, n := range ns {
+		items[i] = fmt.Sprint(n)
+	}
+	return strings.Join(items, ", ")
+}
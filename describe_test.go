@@ -0,0 +1,76 @@
+package plurals
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	for _, tt := range []struct {
+		exp  string
+		want []FormInfo
+	}{
+		{exp: "n != 1", want: []FormInfo{
+			{Form: 0, Description: "1", Examples: []int64{1}},
+			{Form: 1, Description: "all numbers (but not 1)", Examples: []int64{0, 2, 3, 4, 5, 6}},
+		}},
+		{exp: commonRules[7].exp, want: []FormInfo{ // Russian
+			{Form: 0, Description: "numbers ending in 1, except those ending in 11", Examples: []int64{1, 21, 31, 41, 51, 61}},
+			{Form: 1, Description: "numbers ending in 2-4, except those ending in 12-14", Examples: []int64{2, 3, 4, 22, 23, 24}},
+			{Form: 2, Description: "all numbers, except those ending in 1-4, but including those ending in 11-14",
+				Examples: []int64{0, 5, 6, 7, 8, 9}},
+		}},
+		{exp: commonRules[6].exp, want: []FormInfo{ // Lithuanian
+			{Form: 0, Description: "numbers ending in 1, except those ending in 11", Examples: []int64{1, 21, 31, 41, 51, 61}},
+			{Form: 1, Description: "all numbers, except those ending in 0 or 1 and those ending in 12-19", Examples: []int64{2, 3, 4, 5, 6, 7}},
+			{Form: 2, Description: "numbers ending in 0 and those ending in 11-19", Examples: []int64{0, 10, 11, 12, 13, 14}},
+		}},
+		{exp: "n == 0 ? 0 : n % 100 >= 3 && n % 100 <= 10 ? 1 : 2", want: []FormInfo{
+			{Form: 0, Description: "0", Examples: []int64{0}},
+			{Form: 1, Description: "numbers ending in 03-10", Examples: []int64{3, 4, 5, 6, 7, 8}},
+			{Form: 2, Description: "all numbers, except those ending in 03-10 (but not 0)", Examples: []int64{1, 2, 11, 12, 13, 14}},
+		}},
+		{exp: "n % 3 == 1 ? 0 : 1", want: []FormInfo{
+			{Form: 0, Description: "numbers n with n % 3 = 1", Examples: []int64{1, 4, 7, 10, 13, 16}},
+			{Form: 1, Description: "numbers n with n % 3 = 0 or 2", Examples: []int64{0, 2, 3, 5, 6, 8}},
+		}},
+	} {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Describe(exp)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Describe(%q)=%+v, %v\nwant %+v", tt.exp, got, err, tt.want)
+		}
+	}
+	if _, err := Describe(Mul(N(), N())); err == nil {
+		t.Errorf("want error for n * n")
+	}
+}
+
+func TestCheatSheet(t *testing.T) {
+	langs := []Language{{Name: "English", Rule: "n != 1"}, {Name: "Czech <cs>", Rule: "(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2"}}
+	md, err := CheatSheetMarkdown(langs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## English\n\n`n != 1`\n\n| Form | Numbers | Examples |\n|---|---|---|\n" +
+		"| 0 | 1 | 1 |\n| 1 | all numbers (but not 1) | 0, 2, 3, 4, 5, 6 |\n\n" +
+		"## Czech <cs>\n\n`(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2`\n\n| Form | Numbers | Examples |\n|---|---|---|\n" +
+		"| 0 | 1 | 1 |\n| 1 | 2-4 | 2, 3, 4 |\n| 2 | all numbers (but not 1-4) | 0, 5, 6, 7, 8, 9 |\n"
+	if md != want {
+		t.Errorf("got\n%s\nwant\n%s", md, want)
+	}
+	h, err := CheatSheetHTML(langs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(h, "<h2>Czech &lt;cs&gt;</h2>") || !strings.Contains(h, "<tr><td>1</td><td>2-4</td><td>2, 3, 4</td></tr>") {
+		t.Errorf("unexpected html:\n%s", h)
+	}
+	if _, err := CheatSheetMarkdown([]Language{{Name: "bad", Rule: "n +"}}); err == nil {
+		t.Errorf("want compile error")
+	}
+}
@@ -0,0 +1,75 @@
+package plurals
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Env provides the variables of an expression compiled in extended mode.
+type Env interface {
+	Lookup(name string) (int64, bool)
+}
+
+// Vars is an Env backed by a map, e.g. Vars{"n": 3, "m": 2}.
+type Vars map[string]int64
+
+func (v Vars) Lookup(name string) (int64, bool) {
+	val, ok := v[name]
+	return val, ok
+}
+
+// UnboundError is returned when a variable has no value.
+type UnboundError struct {
+	Name string
+}
+
+func (e *UnboundError) Error() string {
+	return fmt.Sprintf("unbound variable %q", e.Name)
+}
+
+// EvalEnv evaluates exp with every variable, including n, looked up in env.
+func EvalEnv(exp Expression, env Env) (int64, error) {
+	e := evaluator[int64]{d: int64Domain{}, vars: func(name string) (int64, error) {
+		if val, ok := env.Lookup(name); ok {
+			return val, nil
+		}
+		return 0, &UnboundError{Name: name}
+	}}
+	return e.eval(exp)
+}
+
+// Variables returns the sorted names of the variables used in exp.
+func Variables(exp Expression) []string {
+	var names []string
+	inspect(exp, func(e Expression) bool {
+		if p, ok := e.(*PrimaryNode); ok && p.Type == TokenTypeIDN {
+			if name := p.Name; name == "" {
+				names = append(names, "n")
+			} else {
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+type int64Domain struct{}
+
+func (int64Domain) num(v int64) int64 { return v }
+
+func (int64Domain) literal(p *PrimaryNode) int64 { return p.Num }
+
+func (int64Domain) bool(b bool) int64 { return b2i(b) }
+
+func (int64Domain) truth(v int64) bool { return i2b(v) }
+
+func (int64Domain) add(x, y int64) int64 { return x + y }
+func (int64Domain) sub(x, y int64) int64 { return x - y }
+func (int64Domain) mul(x, y int64) int64 { return x * y }
+func (int64Domain) quo(x, y int64) int64 { return x / y }
+func (int64Domain) rem(x, y int64) int64 { return x % y }
+
+func (int64Domain) cmp(x, y int64) int { return cmp.Compare(x, y) }
@@ -0,0 +1,55 @@
+package plurals
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEvalEnv(t *testing.T) {
+	// "%d files in %d folders"
+	exp, err := Compile("n == 1 && m == 1 ? 0 : m == 1 ? 1 : n == m ? 2 : 3", Extended())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Variables(exp); !reflect.DeepEqual(got, []string{"m", "n"}) {
+		t.Errorf("Variables=%v", got)
+	}
+	for _, tt := range []struct {
+		env  Vars
+		want int64
+	}{
+		{env: Vars{"n": 1, "m": 1}, want: 0},
+		{env: Vars{"n": 3, "m": 1}, want: 1},
+		{env: Vars{"n": 2, "m": 2}, want: 2},
+		{env: Vars{"n": 3, "m": 2}, want: 3},
+	} {
+		got, err := EvalEnv(exp, tt.env)
+		if err != nil || got != tt.want {
+			t.Errorf("%v: got=%d, err=%v, want=%d", tt.env, got, err, tt.want)
+		}
+	}
+
+	var unbound *UnboundError
+	if _, err := EvalEnv(exp, Vars{"n": 2}); !errors.As(err, &unbound) || unbound.Name != "m" {
+		t.Errorf("want unbound m, got %v", err)
+	}
+	if _, err := exp.Eval(1); !errors.As(err, &unbound) {
+		t.Errorf("Eval should not bind m, got %v", err)
+	}
+	if _, err := Compile("n == 1 && m == 1"); err == nil {
+		t.Errorf("standard mode should reject m")
+	}
+
+	// 只用到 n 时与 Eval 一致
+	for s := range commons {
+		exp, _ := Compile(s, Extended())
+		for n := range int64(200) {
+			want, _ := exp.Eval(n)
+			if got, err := EvalEnv(exp, Vars{"n": n}); err != nil || got != want {
+				t.Errorf("%q n=%d: got=%d, err=%v, want=%d", s, n, got, err, want)
+				break
+			}
+		}
+	}
+}
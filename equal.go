@@ -0,0 +1,102 @@
+package plurals
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"slices"
+)
+
+// Equal reports whether a and b have the same tree, ignoring the wrapper
+// nodes and parentheses the parser creates: `(n % 10) == 1` is equal to
+// `n % 10 == 1`, and `(a + b) + c` to `a + b + c`, but `a + (b + c)` and
+// `n != 1` are different trees.
+func Equal(a, b Expression) bool {
+	va, vb := view(a), view(b)
+	if va.kind != vb.kind || va.num != vb.num || va.name != vb.name ||
+		!slices.Equal(va.ops, vb.ops) || !slices.Equal(va.ranges, vb.ranges) ||
+		len(va.kids) != len(vb.kids) {
+		return false
+	}
+	for i := range va.kids {
+		if !Equal(va.kids[i], vb.kids[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns a hash of the tree of exp, Equal expressions have the same Hash.
+func Hash(exp Expression) uint64 {
+	h := fnv.New64a()
+	hashTo(h, exp)
+	return h.Sum64()
+}
+
+func hashTo(h hash.Hash64, exp Expression) {
+	v := view(exp)
+	fmt.Fprintf(h, "%q %q %d %q %v %d;", v.kind, v.ops, v.num, v.name, v.ranges, len(v.kids))
+	for _, kid := range v.kids {
+		hashTo(h, kid)
+	}
+}
+
+// node is the structural view of an expression compared by Equal.
+type node struct {
+	kind   string
+	ops    []string // 同一节点中依次出现的运算符
+	num    int64
+	name   string
+	ranges []Range
+	kids   []Expression
+}
+
+func view(exp Expression) node {
+	switch e := unwrap(exp).(type) {
+	case *TernaryNode:
+		return node{kind: "?:", kids: []Expression{e.Condition, e.BranchTrue, e.BranchFalse}}
+	case *LogicNode:
+		// (a && b) && c 即 a && b && c
+		kids := e.Exps
+		if len(kids) == 0 {
+			return node{kind: e.Op}
+		}
+		if left, ok := unwrap(kids[0]).(*LogicNode); ok && left.Op == e.Op {
+			kids = slices.Concat(view(left).kids, kids[1:])
+		}
+		return node{kind: e.Op, kids: kids}
+	case *CompareNode:
+		return node{kind: "compare", ops: []string{e.Op}, kids: []Expression{e.Exp, e.Other}}
+	case *BinaryNExp:
+		ops, kids := e.Op, slices.Concat([]Expression{e.Exp}, e.Other)
+		// (a + b) - c 即 a + b - c
+		left, ok := unwrap(e.Exp).(*BinaryNExp)
+		if ok && len(left.Op) > 0 && len(e.Op) > 0 && precOf[left.Op[0]] == precOf[e.Op[0]] {
+			lv := view(left)
+			ops = slices.Concat(lv.ops, ops)
+			kids = slices.Concat(lv.kids, e.Other)
+		}
+		return node{kind: "binary", ops: ops, kids: kids}
+	case *UnaryExp:
+		return node{kind: "!", kids: []Expression{e.Exp}}
+	case *InNode:
+		kind := "in"
+		if e.Not {
+			kind = "not in"
+		}
+		return node{kind: kind, ranges: e.Ranges, kids: []Expression{e.Exp}}
+	case *PrimaryNode:
+		if e.Type == TokenTypeIDN {
+			return node{kind: "var", name: e.Name}
+		}
+		if e.Big != nil {
+			return node{kind: "num", name: e.Big.String()}
+		}
+		return node{kind: "num", num: e.Num}
+	case nil:
+		return node{}
+	default:
+		// 其他实现 Expression 的类型, 按类型与 String() 比较
+		return node{kind: fmt.Sprintf("%T %v", exp, exp)}
+	}
+}
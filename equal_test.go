@@ -0,0 +1,75 @@
+package plurals
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	for _, tt := range []struct {
+		a, b  string
+		equal bool
+	}{
+		{a: "n != 1", b: "(n != 1)", equal: true},
+		{a: "n % 10 == 1", b: "((n % 10)) == (1)", equal: true},
+		{a: "n + 1 - 2", b: "(n + 1) - 2", equal: true},
+		{a: "n == 1 || n == 2 || n == 3", b: "(n == 1 || n == 2) || n == 3", equal: true},
+		{a: "n ? 1 : n ? 2 : 3", b: "n ? 1 : (n ? 2 : 3)", equal: true},
+		{a: "!!n", b: "!(!(n))", equal: true},
+		{a: "n in 1..2, 5", b: "(n) in 1..2,5", equal: true},
+		{a: "n + 1 - 2", b: "n + (1 - 2)", equal: false},
+		{a: "n == 1 || n == 2 || n == 3", b: "n == 1 || (n == 2 || n == 3)", equal: false},
+		{a: "n != 1", b: "1 != n", equal: false},
+		{a: "n != 1", b: "!(n == 1)", equal: false},
+		{a: "n > 1", b: "n >= 1", equal: false},
+		{a: "n", b: "m", equal: false},
+		{a: "n in 1..2", b: "n not in 1..2", equal: false},
+		{a: "n in 1..2", b: "n >= 1 && n <= 2", equal: false},
+	} {
+		a, err := Compile(tt.a, Extended())
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.a, err)
+		}
+		b, err := Compile(tt.b, Extended())
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.b, err)
+		}
+		if got := Equal(a, b); got != tt.equal {
+			t.Errorf("Equal(%q, %q)=%v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+		if got := Hash(a) == Hash(b); got != tt.equal {
+			t.Errorf("Hash(%q)==Hash(%q) is %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+	}
+}
+
+func TestEqualBuilt(t *testing.T) {
+	n := N()
+	for _, tt := range []struct {
+		exp  Expression
+		want string
+	}{
+		{exp: Ne(n, Num(1)), want: "(n != 1)"},
+		{exp: Cond(And(Eq(Mod(n, Num(10)), Num(1)), Ne(Mod(n, Num(100)), Num(11))), Num(0), Num(1)),
+			want: "(n%10==1 && n%100!=11) ? 0 : 1"},
+		{exp: Sub(Add(n, Num(1)), Num(2)), want: "n+1-2"},
+		{exp: Not(Or(Eq(n, Num(1)), Eq(n, Num(2)))), want: "!(n==1||n==2)"},
+	} {
+		exp, err := Compile(tt.want)
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.want, err)
+		}
+		if !Equal(tt.exp, exp) || Hash(tt.exp) != Hash(exp) {
+			t.Errorf("built %v, want the tree of %q", tt.exp, tt.want)
+		}
+	}
+}
+
+func TestEqualHandBuilt(t *testing.T) {
+	empty := &LogicNode{Op: "&&"}
+	if !Equal(empty, &LogicNode{Op: "&&"}) || Equal(empty, &LogicNode{Op: "||"}) || Equal(empty, N()) {
+		t.Errorf("empty logic nodes")
+	}
+	noOps := &BinaryNExp{Exp: &BinaryNExp{Exp: N(), Other: []Expression{Num(1)}}, Other: []Expression{Num(2)}}
+	if !Equal(noOps, noOps) {
+		t.Errorf("binary node without operators")
+	}
+	Hash(empty)
+}
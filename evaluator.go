@@ -0,0 +1,197 @@
+package plurals
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// domain is the integer arithmetic used by an evaluator, so the same tree walk
+// can evaluate an expression over different number types. Operators are
+// applied by arith and compareOp, a domain only provides the primitives.
+type domain[T any] interface {
+	num(v int64) T
+	literal(p *PrimaryNode) T
+	bool(b bool) T
+	truth(v T) bool
+	add(x, y T) T
+	sub(x, y T) T
+	mul(x, y T) T
+	quo(x, y T) T // y 不为 0
+	rem(x, y T) T // y 不为 0
+	cmp(x, y T) int
+}
+
+// arith applies a binary operator, dividing by zero is ErrDivideZero in
+// every domain.
+func arith[T any](d domain[T], op string, x, y T) (T, error) {
+	switch op {
+	case "+":
+		return d.add(x, y), nil
+	case "-":
+		return d.sub(x, y), nil
+	case "*":
+		return d.mul(x, y), nil
+	case "/", "%":
+		if d.cmp(y, d.num(0)) == 0 {
+			var zero T
+			return zero, ErrDivideZero
+		}
+		if op == "/" {
+			return d.quo(x, y), nil
+		}
+		return d.rem(x, y), nil
+	}
+	var zero T
+	return zero, fmt.Errorf("assert failed")
+}
+
+// compareOp applies a comparison operator.
+func compareOp[T any](d domain[T], op string, x, y T) (bool, error) {
+	c := d.cmp(x, y)
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	}
+	return false, fmt.Errorf("assert failed")
+}
+
+// evaluator walks the node types defined in expression.go with domain d.
+type evaluator[T any] struct {
+	d domain[T]
+	n T
+	// vars 不为空时用于查找包括 n 在内的所有变量
+	vars func(name string) (T, error)
+	hook evalHook[T]
+}
+
+// evalHook is called before and after every node is evaluated, wrapper
+// nodes included, see EvalTrace.
+type evalHook[T any] interface {
+	enter(exp Expression)
+	leave(exp Expression, val T, err error)
+}
+
+func (e *evaluator[T]) eval(exp Expression) (T, error) {
+	if e.hook == nil {
+		return e.walk(exp)
+	}
+	e.hook.enter(exp)
+	val, err := e.walk(exp)
+	e.hook.leave(exp, val, err)
+	return val, err
+}
+
+func (e *evaluator[T]) walk(exp Expression) (val T, err error) {
+	switch exp := exp.(type) {
+	case *TernaryNode:
+		if val, err = e.eval(exp.Condition); err != nil {
+			return
+		}
+		if e.d.truth(val) {
+			return e.eval(exp.BranchTrue)
+		}
+		return e.eval(exp.BranchFalse)
+	case *LogicNode:
+		for i, sub := range exp.Exps {
+			if i > 0 {
+				if !e.d.truth(val) && exp.Op == "&&" {
+					return e.d.bool(false), nil
+				}
+				if e.d.truth(val) && exp.Op == "||" {
+					return e.d.bool(true), nil
+				}
+			}
+			if val, err = e.eval(sub); err != nil {
+				return
+			}
+			if i > 0 {
+				val = e.d.bool(e.d.truth(val))
+			}
+		}
+		return
+	case *CompareNode:
+		if val, err = e.eval(exp.Exp); err != nil || exp.Other == nil {
+			return
+		}
+		var other T
+		if other, err = e.eval(exp.Other); err != nil {
+			return
+		}
+		var ok bool
+		if ok, err = compareOp(e.d, exp.Op, val, other); err != nil {
+			return
+		}
+		return e.d.bool(ok), nil
+	case *BinaryNExp:
+		if val, err = e.eval(exp.Exp); err != nil {
+			return
+		}
+		for idx, sub := range exp.Other {
+			var other T
+			if other, err = e.eval(sub); err != nil {
+				return
+			}
+			if val, err = arith(e.d, exp.Op[idx], val, other); err != nil {
+				err = at(divisionAt(exp, idx), err)
+				return
+			}
+		}
+		return
+	case *UnaryExp:
+		if val, err = e.eval(exp.Exp); err != nil {
+			return
+		}
+		if exp.Op == "!" {
+			val = e.d.bool(!e.d.truth(val))
+		}
+		return
+	case *InNode:
+		if val, err = e.eval(exp.Exp); err != nil {
+			return
+		}
+		in := false
+		for _, r := range exp.Ranges {
+			var ge, le bool
+			if ge, err = compareOp(e.d, ">=", val, e.d.num(r.From)); err != nil {
+				return
+			}
+			if le, err = compareOp(e.d, "<=", val, e.d.num(r.To)); err != nil {
+				return
+			}
+			if in = ge && le; in {
+				break
+			}
+		}
+		return e.d.bool(in != exp.Not), nil
+	case *PrimaryNode:
+		switch exp.Type {
+		case TokenTypeIDN:
+			if e.vars != nil {
+				if val, err = e.vars(cmp.Or(exp.Name, "n")); err != nil {
+					err = at(exp.Span, err)
+				}
+				return
+			}
+			if exp.Name != "" {
+				return val, at(exp.Span, &UnboundError{Name: exp.Name})
+			}
+			return e.n, nil
+		case TokenTypeNUM:
+			return e.d.literal(exp), nil
+		case TokenTypeLPA:
+			return e.eval(exp.Exp)
+		}
+	}
+	err = fmt.Errorf("unsupported expression %T", exp)
+	return
+}
@@ -1,36 +1,166 @@
 package plurals
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strings"
+	"sync"
 )
 
+var ErrDivideZero = errors.New("divide zero")
+
 type Expression interface {
 	Eval(n int64) (int64, error)
 }
 
 func Eval(s string, n int64) (int64, error) {
+	r, err := ruleOf(s)
+	if err != nil {
+		return 0, err
+	}
+	return r.Eval(n)
+}
+
+func eval(s string, n int64) (int64, error) {
+	exp, err := compileCached(s)
+	if err != nil {
+		return 0, err
+	}
+	return exp.Eval(n)
+}
+
+// Rule is a plural expression prepared for repeated evaluation,
+// Eval on a Rule does not allocate.
+type Rule struct {
+	exp  Expression // 编译 s 得到的语法树, 位置与 s 对应
+	eval Expression // 求值用的语法树, 规范形式相同的规则共用
+	fn   func(n int64) int64
+}
+
+// NewRule compiles s for repeated evaluation. Well-known rules and rules
+// added by Register are evaluated by native Go functions. Rules with the same
+// Canonical form share one evaluation tree, unless evaluating them may fail.
+func NewRule(s string) (*Rule, error) {
+	// 编译原始输入, 错误中的位置与 s 对应
+	exp, err := compileCached(s)
+	if err != nil {
+		return nil, err
+	}
+	// Register 注册的实现优先于常用规则
+	key := normalize(s)
+	mu.RLock()
+	fn := custom[key]
+	mu.RUnlock()
+	sh := sharedOf(exp)
+	if fn == nil {
+		// 与已知规则等价时同样使用原生实现, 如 (n != 1)
+		fn = sh.fn
+	}
+	if fn == nil {
+		fn = commons[key]
+	}
+	eval := exp
+	if !mayFail(exp) {
+		// 不会出错时求值结果与位置无关, 可以共用
+		eval = sh.exp
+	}
+	return &Rule{exp: exp, eval: eval, fn: fn}, nil
+}
+
+// shared is what the rules with the same Canonical form have in common.
+type shared struct {
+	exp Expression          // 规范形式
+	fn  func(n int64) int64 // 等价规则的原生实现, 没有时为 nil
+}
+
+// sharedOf returns the shared entry for the Canonical form of exp.
+func sharedOf(exp Expression) shared {
+	c := Canonicalize(exp)
+	key := fmt.Sprint(c)
+	mu.RLock()
+	sh, ok := canons[key]
+	mu.RUnlock()
+	if ok {
+		return sh
+	}
+	sh = shared{exp: c, fn: recognize(exp)}
+	mu.Lock()
+	store(canons, key, sh)
+	mu.Unlock()
+	return sh
+}
+
+func (r *Rule) Eval(n int64) (int64, error) {
+	if r.fn != nil {
+		return r.fn(n), nil
+	}
+	return r.eval.Eval(n)
+}
+
+// Expression returns the compiled expression.
+func (r *Rule) Expression() Expression {
+	return r.exp
+}
+
+func normalize(s string) string {
 	s = strings.ReplaceAll(s, " ", "")
 	s = strings.ReplaceAll(s, "\t", "")
-	if f, ok := commons[s]; ok {
-		return f(n), nil
+	return s
+}
+
+// maxCached bounds each cache below, a full cache is cleared: the keys may be
+// untrusted Plural-Forms headers.
+const maxCached = 1024
+
+var (
+	mu     sync.RWMutex
+	rules  = map[string]*Rule{}      // 原始字符串 -> Rule
+	cache  = map[string]Expression{} // 原始字符串 -> Expression, 不去除空白: 位置与输入对应
+	canons = map[string]shared{}     // Canonical -> 等价规则共用的求值树与原生实现
+)
+
+func ruleOf(s string) (*Rule, error) {
+	mu.RLock()
+	r, ok := rules[s]
+	mu.RUnlock()
+	if ok {
+		return r, nil
 	}
-	return eval(s, n)
+	r, err := NewRule(s)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	store(rules, s, r)
+	mu.Unlock()
+	return r, nil
 }
 
-func eval(s string, n int64) (int64, error) {
-	if exp, ok := cache[s]; ok {
-		return exp.Eval(n)
+func compileCached(s string) (Expression, error) {
+	mu.RLock()
+	exp, ok := cache[s]
+	mu.RUnlock()
+	if ok {
+		return exp, nil
 	}
 	exp, err := Compile(s)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	cache[s] = exp
-	return exp.Eval(n)
+	mu.Lock()
+	store(cache, s, exp)
+	mu.Unlock()
+	return exp, nil
 }
 
-var cache = map[string]Expression{}
+// store 在 m 已满时先清空, 调用者持有 mu
+func store[V any](m map[string]V, key string, val V) {
+	if _, ok := m[key]; !ok && len(m) >= maxCached {
+		clear(m)
+	}
+	m[key] = val
+}
 
 const (
 	nFalse = 0
@@ -52,6 +182,7 @@ type TernaryNode struct {
 	Condition   Expression
 	BranchTrue  Expression
 	BranchFalse Expression
+	Span        Span
 }
 
 func (e *TernaryNode) Eval(n int64) (int64, error) {
@@ -72,40 +203,34 @@ func (e *TernaryNode) String() string {
 type LogicNode struct {
 	Op   string
 	Exps []Expression
+	Span Span
 }
 
 func (e *LogicNode) Eval(n int64) (val int64, err error) {
 	op := e.Op
 	for i, exp := range e.Exps {
-		if i == 0 {
-			val, err = exp.Eval(n)
-			if err != nil {
-				return 0, err
+		if i > 0 {
+			// 短路求值: 与 C 一致, 右侧不再计算
+			if !i2b(val) && op == "&&" {
+				return nFalse, nil
 			}
+			if i2b(val) && op == "||" {
+				return nTrue, nil
+			}
+		}
+		val, err = exp.Eval(n)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
 			continue
 		}
 		switch op {
-		case "&&":
-			i, err := exp.Eval(n)
-			if err != nil {
-				return 0, err
-			}
-			val = b2i(i2b(val) && i2b(i))
-		case "||":
-			i, err := exp.Eval(n)
-			if err != nil {
-				return 0, err
-			}
-			val = b2i(i2b(val) || i2b(i))
+		case "&&", "||":
+			val = b2i(i2b(val))
 		default:
 			return 0, fmt.Errorf("assert failed")
 		}
-		if !i2b(val) && op == "&&" {
-			return nFalse, nil
-		}
-		if i2b(val) && op == "||" {
-			return nTrue, nil
-		}
 	}
 	return val, nil
 }
@@ -125,6 +250,7 @@ type CompareNode struct {
 	Exp   Expression
 	Op    string
 	Other Expression
+	Span  Span
 }
 
 func (e *CompareNode) Eval(n int64) (int64, error) {
@@ -137,22 +263,8 @@ func (e *CompareNode) Eval(n int64) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		switch e.Op {
-		case "==":
-			return b2i(val == i), nil
-		case "!=":
-			return b2i(val != i), nil
-		case ">":
-			return b2i(val > i), nil
-		case ">=":
-			return b2i(val >= i), nil
-		case "<":
-			return b2i(val < i), nil
-		case "<=":
-			return b2i(val <= i), nil
-		default:
-			return 0, fmt.Errorf("assert failed")
-		}
+		ok, err := compareOp[int64](int64Domain{}, e.Op, val, i)
+		return b2i(ok), err
 	}
 	return val, nil
 }
@@ -170,6 +282,7 @@ type BinaryNExp struct {
 	Exp   Expression
 	Op    []string
 	Other []Expression
+	Span  Span
 }
 
 func (e *BinaryNExp) Eval(n int64) (int64, error) {
@@ -182,25 +295,8 @@ func (e *BinaryNExp) Eval(n int64) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		switch e.Op[idx] {
-		case "+":
-			val = val + i
-		case "-":
-			val = val - i
-		case "*":
-			val = val * i
-		case "/":
-			if i == 0 {
-				return 0, fmt.Errorf("divide zero")
-			}
-			val = val / i
-		case "%":
-			if i == 0 {
-				return 0, fmt.Errorf("divide zero")
-			}
-			val = val % i
-		default:
-			return 0, fmt.Errorf("assert failed")
+		if val, err = arith[int64](int64Domain{}, e.Op[idx], val, i); err != nil {
+			return 0, at(divisionAt(e, idx), err)
 		}
 	}
 	return val, nil
@@ -216,8 +312,9 @@ func (e *BinaryNExp) String() string {
 }
 
 type UnaryExp struct {
-	Op  string
-	Exp Expression
+	Op   string
+	Exp  Expression
+	Span Span
 }
 
 func (e *UnaryExp) Eval(n int64) (int64, error) {
@@ -243,11 +340,17 @@ type PrimaryNode struct {
 	Type TokenType
 	Num  int64
 	Exp  Expression
+	Name string   // 扩展语法中 n 以外的变量名, 为空时表示 n
+	Big  *big.Int // 超出 int64 的数字字面量, Num 是截断到 64 位的值
+	Span Span
 }
 
 func (e *PrimaryNode) Eval(n int64) (int64, error) {
 	switch e.Type {
 	case TokenTypeIDN:
+		if e.Name != "" {
+			return 0, at(e.Span, &UnboundError{Name: e.Name})
+		}
 		return n, nil
 	case TokenTypeNUM:
 		return e.Num, nil
@@ -260,11 +363,145 @@ func (e *PrimaryNode) Eval(n int64) (int64, error) {
 func (e *PrimaryNode) String() string {
 	switch e.Type {
 	case TokenTypeIDN:
+		if e.Name != "" {
+			return e.Name
+		}
 		return "n"
 	case TokenTypeNUM:
+		if e.Big != nil {
+			return e.Big.String()
+		}
 		return fmt.Sprintf("%v", e.Num)
 	case TokenTypeLPA:
 		return fmt.Sprintf("( %v )", e.Exp)
 	}
 	return ""
 }
+
+// children returns the direct sub expressions of exp.
+func children(exp Expression) []Expression {
+	switch exp := exp.(type) {
+	case *TernaryNode:
+		return []Expression{exp.Condition, exp.BranchTrue, exp.BranchFalse}
+	case *LogicNode:
+		return exp.Exps
+	case *CompareNode:
+		if exp.Other == nil {
+			return []Expression{exp.Exp}
+		}
+		return []Expression{exp.Exp, exp.Other}
+	case *BinaryNExp:
+		return append([]Expression{exp.Exp}, exp.Other...)
+	case *UnaryExp:
+		return []Expression{exp.Exp}
+	case *PrimaryNode:
+		if exp.Type == TokenTypeLPA {
+			return []Expression{exp.Exp}
+		}
+	case *InNode:
+		return []Expression{exp.Exp}
+	}
+	return nil
+}
+
+// mapChildren returns a shallow copy of exp with each direct sub expression
+// replaced by f(sub).
+func mapChildren(exp Expression, f func(Expression) Expression) Expression {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		c := *e
+		c.Condition, c.BranchTrue, c.BranchFalse = f(e.Condition), f(e.BranchTrue), f(e.BranchFalse)
+		return &c
+	case *LogicNode:
+		c := *e
+		c.Exps = mapList(e.Exps, f)
+		return &c
+	case *CompareNode:
+		c := *e
+		c.Exp = f(e.Exp)
+		if e.Other != nil {
+			c.Other = f(e.Other)
+		}
+		return &c
+	case *BinaryNExp:
+		c := *e
+		c.Exp, c.Other = f(e.Exp), mapList(e.Other, f)
+		return &c
+	case *UnaryExp:
+		c := *e
+		c.Exp = f(e.Exp)
+		return &c
+	case *PrimaryNode:
+		c := *e
+		if e.Type == TokenTypeLPA {
+			c.Exp = f(e.Exp)
+		}
+		return &c
+	case *InNode:
+		c := *e
+		c.Exp = f(e.Exp)
+		return &c
+	}
+	return exp
+}
+
+func mapList(list []Expression, f func(Expression) Expression) []Expression {
+	if list == nil {
+		return nil
+	}
+	out := make([]Expression, len(list))
+	for i, exp := range list {
+		out[i] = f(exp)
+	}
+	return out
+}
+
+// inspect calls f for exp and its sub expressions in depth-first order,
+// until f returns false.
+func inspect(exp Expression, f func(Expression) bool) bool {
+	if !f(exp) {
+		return false
+	}
+	for _, sub := range children(exp) {
+		if !inspect(sub, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// unwrap removes the wrapper nodes the parser creates for every precedence
+// level, e.g. a LogicNode with a single operand, or a parenthesized expression.
+func unwrap(exp Expression) Expression {
+	for {
+		switch e := exp.(type) {
+		case *LogicNode:
+			if len(e.Exps) != 1 {
+				return exp
+			}
+			exp = e.Exps[0]
+		case *CompareNode:
+			if e.Other != nil {
+				return exp
+			}
+			exp = e.Exp
+		case *BinaryNExp:
+			if len(e.Other) != 0 {
+				return exp
+			}
+			exp = e.Exp
+		case *UnaryExp:
+			if e.Op == "!" {
+				return exp
+			}
+			exp = e.Exp
+		case *PrimaryNode:
+			if e.Type != TokenTypeLPA {
+				return exp
+			}
+			exp = e.Exp
+		default:
+			return exp
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package plurals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Range is the closed interval From..To of the extension syntax.
+type Range struct {
+	From, To int64
+}
+
+func (r Range) String() string {
+	if r.From == r.To {
+		return fmt.Sprintf("%d", r.From)
+	}
+	return fmt.Sprintf("%d..%d", r.From, r.To)
+}
+
+// InNode is the extended `exp in 2..4, 7` or `exp not in 12..14` test.
+type InNode struct {
+	Exp    Expression
+	Not    bool
+	Ranges []Range
+	Span   Span
+}
+
+func (e *InNode) Eval(n int64) (int64, error) {
+	val, err := e.Exp.Eval(n)
+	if err != nil {
+		return 0, err
+	}
+	in := false
+	for _, r := range e.Ranges {
+		if val >= r.From && val <= r.To {
+			in = true
+			break
+		}
+	}
+	return b2i(in != e.Not), nil
+}
+
+func (e *InNode) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v ", e.Exp)
+	if e.Not {
+		sb.WriteString("not ")
+	}
+	sb.WriteString("in ")
+	for i, r := range e.Ranges {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(r.String())
+	}
+	return sb.String()
+}
+
+// Standard returns the same comparisons written in standard GNU syntax:
+// `x in 2..4, 7` is `( x >= 2 && x <= 4 || x == 7 )`.
+func (e *InNode) Standard() Expression {
+	var or []Expression
+	for _, r := range e.Ranges {
+		if r.From == r.To {
+			or = append(or, &CompareNode{Exp: e.Exp, Op: "==", Other: num(r.From)})
+			continue
+		}
+		or = append(or, &LogicNode{Op: "&&", Exps: []Expression{
+			&CompareNode{Exp: e.Exp, Op: ">=", Other: num(r.From)},
+			&CompareNode{Exp: e.Exp, Op: "<=", Other: num(r.To)},
+		}})
+	}
+	var node Expression = &PrimaryNode{Type: TokenTypeLPA, Exp: &LogicNode{Op: "||", Exps: or}}
+	if e.Not {
+		node = &UnaryExp{Op: "!", Exp: node}
+	}
+	return node
+}
+
+func num(v int64) *PrimaryNode {
+	return &PrimaryNode{Type: TokenTypeNUM, Num: v}
+}
+
+// Standard replaces every extension node in exp with standard syntax.
+func Standard(exp Expression) Expression {
+	if in, ok := exp.(*InNode); ok {
+		in = &InNode{Exp: Standard(in.Exp), Not: in.Not, Ranges: in.Ranges}
+		return in.Standard()
+	}
+	return mapChildren(exp, Standard)
+}
+
+// GNUString prints exp in the syntax of GNU gettext, suitable for a
+// Plural-Forms header even if exp was compiled in extended mode.
+func GNUString(exp Expression) string {
+	return fmt.Sprintf("%v", Standard(exp))
+}
+
+// parseIn: ('in'|'not in') range ( ',' range )*
+func parseIn(p *parser, idx int, left Expression, op string) (index int, node Expression, err error) {
+	index = idx
+	in := &InNode{Exp: left, Not: op == "not in"}
+	for {
+		var from, to Token
+		from, index, err = p.consume(index, TokenTypeNUM, "")
+		if err != nil {
+			return
+		}
+		to = from
+		if token, ok := p.get(index); ok && token.Type == TokenTypeRNG {
+			to, index, err = p.consume(index+1, TokenTypeNUM, "")
+			if err != nil {
+				return
+			}
+			if to.Number < from.Number {
+				err = fmt.Errorf("empty range %d..%d at column [%d:%d]",
+					from.Number, to.Number, from.Start, to.End)
+				return
+			}
+		}
+		in.Ranges = append(in.Ranges, Range{From: from.Number, To: to.Number})
+		in.Span = Span{Start: SpanOf(left).Start, End: to.End}
+		if token, ok := p.get(index); !ok || token.Type != TokenTypeSEP {
+			break
+		}
+		index++
+	}
+	return index, in, nil
+}
@@ -0,0 +1,104 @@
+package plurals
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExtended(t *testing.T) {
+	russian, _ := Compile("n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2")
+	for _, tt := range []struct {
+		exp  string
+		gnu  string
+		same Expression // 与之等价
+	}{
+		{
+			exp:  "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 in 2..4 && n % 100 not in 12..14 ? 1 : 2",
+			gnu:  "n % 10 == 1 && n % 100 != 11 ? 0 : ( n % 10 >= 2 && n % 10 <= 4 ) && !( n % 100 >= 12 && n % 100 <= 14 ) ? 1 : 2",
+			same: russian,
+		},
+		{
+			exp:  "n % 10 in 1 && n % 100 not in 11 ? 0 : n % 10 in 2..4 && n % 100 not in 10..19 ? 1 : 2",
+			gnu:  "( n % 10 == 1 ) && !( n % 100 == 11 ) ? 0 : ( n % 10 >= 2 && n % 10 <= 4 ) && !( n % 100 >= 10 && n % 100 <= 19 ) ? 1 : 2",
+			same: russian,
+		},
+		{
+			exp: "n in 0, 2..3, 10 ? 1 : 0",
+			gnu: "( n == 0 || n >= 2 && n <= 3 || n == 10 ) ? 1 : 0",
+		},
+		{
+			exp: "n < 5 not  in 0 == 0",
+			gnu: "!( n < 5 == 0 ) == 0",
+		},
+	} {
+		exp, err := Compile(tt.exp, Extended())
+		if err != nil {
+			t.Errorf("compile %q: %+v", tt.exp, err)
+			continue
+		}
+		if got := GNUString(exp); got != tt.gnu {
+			t.Errorf("GNUString(%q)=%q, want %q", tt.exp, got, tt.gnu)
+		}
+		gnu, err := Compile(GNUString(exp))
+		if err != nil {
+			t.Errorf("compile GNUString(%q): %+v", tt.exp, err)
+			continue
+		}
+		again, err := Compile(fmt.Sprintf("%v", exp), Extended())
+		if err != nil {
+			t.Errorf("compile %v: %+v", exp, err)
+			continue
+		}
+		for n := range int64(300) {
+			want, _ := gnu.Eval(n)
+			if got, _ := exp.Eval(n); got != want {
+				t.Errorf("%q n=%d: got=%d, want=%d", tt.exp, n, got, want)
+				break
+			}
+			if got, _ := again.Eval(n); got != want {
+				t.Errorf("%v n=%d: got=%d, want=%d", again, n, got, want)
+				break
+			}
+			if got, _ := EvalGNU(exp, uint64(n)); int64(got) != want {
+				t.Errorf("EvalGNU %q n=%d: got=%d, want=%d", tt.exp, n, got, want)
+				break
+			}
+			if tt.same != nil {
+				if got, _ := tt.same.Eval(n); got != want {
+					t.Errorf("%q n=%d: got=%d, want=%d", tt.exp, n, want, got)
+					break
+				}
+			}
+		}
+	}
+	for _, s := range []string{
+		"n % 10 in 2..4", // 默认不开启
+		"n % 10 in 4..2", // 空区间
+		"n % 10 not 2",   // 缺少 in
+		"n % 10 in",      // 缺少区间
+		"n % 10 in 1, ",  // 多余的逗号
+		"n % 10 in 1..",  // 缺少上界
+		"N != 1",         // N 只在宽松模式下可用
+	} {
+		opts := []Option{Extended()}
+		if s == "n % 10 in 2..4" {
+			opts = nil
+		}
+		if exp, err := Compile(s, opts...); err == nil {
+			t.Errorf("%q: want error, got %v", s, exp)
+		}
+	}
+}
+
+func TestExtendedUppercaseN(t *testing.T) {
+	var warnings []string
+	exp, err := Compile("N != 1", Extended(), Lenient(), WithWarning(func(w Warning) {
+		warnings = append(warnings, w.String())
+	}))
+	if err != nil || len(warnings) != 1 {
+		t.Fatalf("err=%v, warnings=%q", err, warnings)
+	}
+	if got, _ := exp.Eval(1); got != 0 {
+		t.Errorf("Eval(1)=%d, want 0", got)
+	}
+}
@@ -0,0 +1,97 @@
+package plurals
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+)
+
+// ErrSearchLimit is returned by FindN and AllN when exp is not periodic and
+// maxCompare numbers were tried: more n may exist.
+var ErrSearchLimit = errors.New("search limit reached")
+
+// FindN returns the first limit n >= from, in increasing order, that select form.
+// When exp is periodic (see EvalRange) fewer than limit results mean no
+// other n exists.
+func FindN(exp Expression, form, from int64, limit int) (ns []int64, err error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	for n, err := range AllN(exp, form, from) {
+		if err != nil {
+			return ns, err
+		}
+		if ns = append(ns, n); len(ns) == limit {
+			break
+		}
+	}
+	return ns, nil
+}
+
+// AllN yields every n >= from that selects form, in increasing order. It stops
+// after an error: an evaluation error, or ErrSearchLimit if exp is not periodic.
+func AllN(exp Expression, form, from int64) iter.Seq2[int64, error] {
+	return func(yield func(int64, error) bool) {
+		match := func(n int64) bool { return yield(n, nil) }
+		fail := func(err error) { yield(0, err) }
+		s, ok := analyze(exp)
+		if !ok {
+			for i, n := 0, from; i < maxCompare; i, n = i+1, n+1 {
+				val, err := exp.Eval(n)
+				if err != nil {
+					fail(fmt.Errorf("n=%d: %w", n, err))
+					return
+				}
+				if val == form && !match(n) || n == math.MaxInt64 {
+					return
+				}
+			}
+			fail(ErrSearchLimit)
+			return
+		}
+		// 每一段只计算一个周期, 没有的形式很快就能确定
+		for lo, hi := range s.pieces(from) {
+			if cont, err := matchPeriodic(exp, form, lo, hi, s.period, match); err != nil || !cont {
+				if err != nil {
+					fail(err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// matchPeriodic yields the n in [from, to] that select form, where
+// f(m) == f(m+period): only the first period is evaluated.
+func matchPeriodic(exp Expression, form, from, to, period int64, yield func(int64) bool) (bool, error) {
+	span := uint64(to) - uint64(from)
+	var offsets []uint64
+	for off := uint64(0); off < uint64(period) && off <= span; off++ {
+		n := from + int64(off)
+		val, err := exp.Eval(n)
+		if err != nil {
+			return false, fmt.Errorf("n=%d: %w", n, err)
+		}
+		if val == form {
+			offsets = append(offsets, off)
+		}
+	}
+	if len(offsets) == 0 {
+		return true, nil
+	}
+	for base := uint64(0); base <= span; base += uint64(period) {
+		for _, off := range offsets {
+			if base+off > span {
+				return true, nil
+			}
+			if !yield(from + int64(base+off)) {
+				return false, nil
+			}
+		}
+		if base+uint64(period) < base {
+			break
+		}
+	}
+	return true, nil
+}
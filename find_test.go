@@ -0,0 +1,72 @@
+package plurals
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFindN(t *testing.T) {
+	ru := commonRules[7].exp
+	for _, tt := range []struct {
+		exp   string
+		form  int64
+		from  int64
+		limit int
+		want  []int64
+		err   error
+	}{
+		{exp: ru, form: 1, from: 5, limit: 1, want: []int64{22}},
+		{exp: ru, form: 2, from: 0, limit: 3, want: []int64{0, 5, 6}},
+		{exp: ru, form: 0, from: 1000, limit: 4, want: []int64{1001, 1021, 1031, 1041}},
+		{exp: commonRules[11].exp, form: 5, from: 1000, limit: 2, want: []int64{1000, 1001}}, // Arabic
+		{exp: "n == 3 ? 1 : 0", form: 1, from: 0, limit: 5, want: []int64{3}},
+		{exp: "n == 3 ? 1 : 0", form: 1, from: 4, limit: 5, want: nil},
+		{exp: "n != 1", form: 7, from: math.MinInt64, limit: 5, want: nil},
+		{exp: "n % 7 == 3", form: 1, from: -10, limit: 3, want: []int64{3, 10, 17}}, // 负数的余数为负,
+		{exp: "n % 7 == 3", form: 1, from: math.MaxInt64 - 20, limit: 5, want: []int64{math.MaxInt64 - 18, math.MaxInt64 - 11, math.MaxInt64 - 4}},
+		// 阈值很大时按段跳过, 不逐个计算
+		{exp: "n > 1000000000000 ? 1 : 0", form: 1, from: 0, limit: 1, want: []int64{1000000000001}},
+		{exp: "n > 1000000000000 ? 1 : 0", form: 5, from: 0, limit: 1, want: nil},
+		{exp: "n > 1000000000000 && n < 1000000000100 ? n % 10 : 11", form: 3, from: 0, limit: 3,
+			want: []int64{1000000000003, 1000000000013, 1000000000023}},
+		{exp: "n > 0 - 100 && n < 100 ? n % 10 == 9 : 2", form: 1, from: -150, limit: 3, want: []int64{9, 19, 29}},
+		{exp: "n * n == 49", form: 1, from: 0, limit: 1, want: []int64{7}},
+		{exp: "n * n == 49", form: 1, from: 8, limit: 1, want: nil, err: ErrSearchLimit},
+		{exp: "n ? 10 / (n - 5) : 0", form: 3, from: 0, limit: 1, want: nil, err: ErrDivideZero},
+	} {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := FindN(exp, tt.form, tt.from, tt.limit)
+		if !reflect.DeepEqual(got, tt.want) || !errors.Is(err, tt.err) {
+			t.Errorf("FindN(%q, %d, %d, %d)=%v, %v, want %v, %v", tt.exp, tt.form, tt.from, tt.limit, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestAllN(t *testing.T) {
+	exp, err := Compile(commonRules[7].exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与逐个计算一致
+	var want []int64
+	for n := int64(-300); n < 300; n++ {
+		if val, _ := exp.Eval(n); val == 1 {
+			want = append(want, n)
+		}
+	}
+	var got []int64
+	for n, err := range AllN(exp, 1, -300) {
+		if err != nil || n >= 300 {
+			break
+		}
+		got = append(got, n)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package plurals
+
+import "cmp"
+
+// EvalGNU evaluates exp the way GNU libintl does: n and every intermediate
+// value are `unsigned long int`, so arithmetic wraps around, comparisons are
+// unsigned and number literals are truncated to 64 bits.
+// libintl raises SIGFPE on division by zero, here ErrDivideZero is returned.
+// Negative counts should be converted with uint64(n), the same as a C cast.
+func EvalGNU(exp Expression, n uint64) (uint64, error) {
+	e := evaluator[uint64]{d: gnuDomain{}, n: n}
+	return e.eval(exp)
+}
+
+// IndexGNU is EvalGNU followed by the check done by ngettext:
+// an index out of [0, nplurals) selects form 0.
+func IndexGNU(exp Expression, n uint64, nplurals uint64) (uint64, error) {
+	index, err := EvalGNU(exp, n)
+	if err != nil {
+		return 0, err
+	}
+	if index >= nplurals {
+		return 0, nil
+	}
+	return index, nil
+}
+
+type gnuDomain struct{}
+
+func (gnuDomain) num(v int64) uint64 { return uint64(v) }
+
+func (gnuDomain) literal(p *PrimaryNode) uint64 { return uint64(p.Num) }
+
+func (gnuDomain) bool(b bool) uint64 {
+	if b {
+		return nTrue
+	}
+	return nFalse
+}
+
+func (gnuDomain) truth(v uint64) bool { return v != nFalse }
+
+func (gnuDomain) add(x, y uint64) uint64 { return x + y }
+func (gnuDomain) sub(x, y uint64) uint64 { return x - y }
+func (gnuDomain) mul(x, y uint64) uint64 { return x * y }
+func (gnuDomain) quo(x, y uint64) uint64 { return x / y }
+func (gnuDomain) rem(x, y uint64) uint64 { return x % y }
+
+func (gnuDomain) cmp(x, y uint64) int { return cmp.Compare(x, y) }
@@ -0,0 +1,90 @@
+package plurals
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// gnuVectors are checked against a C program evaluating the same expressions
+// with `unsigned long int n` (LP64), as libintl's plural_eval does.
+var gnuVectors = []struct {
+	exp  string
+	n    uint64
+	want uint64
+	err  error
+}{
+	{exp: "n - 1", n: 0, want: math.MaxUint64},
+	{exp: "n + 1", n: math.MaxUint64, want: 0},
+	{exp: "n == 0 || 10 / n > 1", n: 0, want: 1},
+	{exp: "n != 0 && 10 % n", n: 0, want: 0},
+	{exp: "n / 0", n: 1, err: ErrDivideZero},
+	{exp: "n % ( n - n )", n: 7, err: ErrDivideZero},
+	{exp: "n > 1", n: uint64(math.MaxUint64), want: 1},
+	{exp: "n != 1", n: uint64(math.MaxUint64), want: 1},
+	{exp: "n % 10", n: uint64(math.MaxUint64), want: 5},
+	{exp: "n % 100", n: uint64(math.MaxUint64), want: 15},
+	{exp: "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2",
+		n: 18446744073709551611, want: 2},
+	{exp: "n % 10 == 1 && n % 100 != 11 ? 0 : n != 0 ? 1 : 2", n: 18446744073709551611, want: 1},
+	{exp: "n == 1 ? 0 : ( n == 0 || ( n % 100 > 0 && n % 100 < 20 ) ) ? 1 : 2", n: 18446744073709551611, want: 1},
+	{exp: "n * 2 / 2", n: 1 << 63, want: 0},
+	{exp: "n % 100 >= 11", n: 1 << 63, want: 0},
+	{exp: "( n - 2 ) < 3", n: 1, want: 0},
+	{exp: "!n", n: 1, want: 0},
+	{exp: "!n", n: 0, want: 1},
+	{exp: "n < 5 ? 0 : 1", n: 3, want: 0},
+	{exp: "n < 5 ? 0 : 1", n: 18446744073709551613, want: 1},
+	{exp: "18446744073709551617 == 1", n: 0, want: 1},
+}
+
+func TestEvalGNU(t *testing.T) {
+	for _, tt := range gnuVectors {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Errorf("compile %q: %+v", tt.exp, err)
+			continue
+		}
+		got, err := EvalGNU(exp, tt.n)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q n=%d: err=%v, want %v", tt.exp, tt.n, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q n=%d: got=%d, want=%d", tt.exp, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEvalGNUCommons(t *testing.T) {
+	for s, f := range commons {
+		exp, err := Compile(s)
+		if err != nil {
+			t.Fatalf("compile %q: %+v", s, err)
+		}
+		for n := range uint64(1000) {
+			got, err := EvalGNU(exp, n)
+			if err != nil || got != uint64(f(int64(n))) {
+				t.Errorf("%q n=%d: got=%d, err=%v, want=%d", s, n, got, err, f(int64(n)))
+				break
+			}
+		}
+	}
+}
+
+func TestIndexGNU(t *testing.T) {
+	exp, _ := Compile("n")
+	for _, tt := range []struct {
+		n, nplurals, want uint64
+	}{
+		{n: 0, nplurals: 2, want: 0},
+		{n: 1, nplurals: 2, want: 1},
+		{n: 2, nplurals: 2, want: 0},
+		{n: math.MaxUint64, nplurals: 2, want: 0},
+	} {
+		got, err := IndexGNU(exp, tt.n, tt.nplurals)
+		if err != nil || got != tt.want {
+			t.Errorf("n=%d nplurals=%d: got=%d, err=%v, want=%d", tt.n, tt.nplurals, got, err, tt.want)
+		}
+	}
+}
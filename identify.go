@@ -0,0 +1,94 @@
+package plurals
+
+import (
+	"slices"
+	"sync"
+)
+
+// maxCompare bounds how many n equivalent evaluates.
+const maxCompare = 1 << 22
+
+// maxRecognize bounds the shape of the rules recognize compares: NewRule
+// calls it for every new rule, which may come from an untrusted header.
+// The well-known rules are far smaller.
+const maxRecognize = 1 << 12
+
+// Identify returns the language families of the well-known rules that are
+// equivalent to exp, e.g. `(n != 1)` and `n == 1 ? 0 : 1` both give the
+// Germanic family and others using `n != 1`.
+func Identify(exp Expression) []string {
+	var families []string
+	for _, r := range knownRules() {
+		if equivalent(exp, r.exp) {
+			families = append(families, r.families...)
+		}
+	}
+	return families
+}
+
+// knownRule is a compiled rule with a native implementation.
+type knownRule struct {
+	exp      Expression
+	families []string
+	fn       func(n int64) int64
+}
+
+var knownRules = sync.OnceValue(func() []knownRule {
+	rules := make([]knownRule, 0, len(commonRules))
+	for _, r := range commonRules {
+		exp, err := NoLimits.Compile(r.exp)
+		if err != nil {
+			panic("plurals: invalid common rule " + r.exp + ": " + err.Error())
+		}
+		rules = append(rules, knownRule{exp: exp, families: r.families, fn: r.fn})
+	}
+	return rules
+})
+
+// recognize returns the native implementation of a rule equivalent to exp,
+// preferring the rules added by Register. Rules with large thresholds or
+// periods are not compared, they keep evaluating the expression.
+func recognize(exp Expression) func(n int64) int64 {
+	mu.RLock()
+	rules := slices.Concat(registered, knownRules())
+	mu.RUnlock()
+	for _, r := range rules {
+		if equivalentWithin(exp, r.exp, maxRecognize) {
+			return r.fn
+		}
+	}
+	return nil
+}
+
+// equivalent reports whether a and b give the same result (or both fail)
+// for every n. It is exact, using the periodicity found by analyze, and
+// returns false when that is not possible.
+func equivalent(a, b Expression) bool {
+	return equivalentWithin(a, b, maxCompare)
+}
+
+// equivalentWithin is equivalent, false when more than limit n would be
+// compared.
+func equivalentWithin(a, b Expression, limit uint64) bool {
+	sa, ok := analyze(a)
+	if !ok {
+		return false
+	}
+	sb, ok := analyze(b)
+	if !ok {
+		return false
+	}
+	s := shape{lo: min(sa.lo, sb.lo), hi: max(sa.hi, sb.hi), period: lcm(sa.period, sb.period)}
+	if !s.within(limit) {
+		return false
+	}
+	// [lo-period, hi+period] 之外的 n 与其中某个 n 结果相同
+	for n := s.lo - s.period; n <= s.hi+s.period; n++ {
+		x, errX := a.Eval(n)
+		y, errY := b.Eval(n)
+		if x != y || (errX == nil) != (errY == nil) {
+			return false
+		}
+	}
+	return true
+}
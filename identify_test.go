@@ -0,0 +1,82 @@
+package plurals
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	for _, tt := range []struct {
+		exp  string
+		want []string
+	}{
+		{exp: "(n != 1)", want: commonRules[1].families},
+		{exp: "n == 1 ? 0 : 1", want: commonRules[1].families},
+		{exp: "n>1 ? 1 : 0", want: []string{"Romanic family: Brazilian Portuguese, French"}},
+		{exp: "1 < n", want: []string{"Romanic family: Brazilian Portuguese, French"}},
+		{exp: "n % 1", want: []string{"Asian family: Japanese, Vietnamese, Korean", "Tai-Kadai family: Thai"}},
+		{exp: "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 in 2..4 && n % 100 not in 12..14 ? 1 : 2",
+			want: []string{"Slavic family: Russian, Ukrainian, Belarusian, Serbian, Croatian"}},
+		{exp: "n == 1 ? 0 : n >= 2 && n <= 4 ? 1 : 2", want: []string{"Slavic family: Czech, Slovak"}},
+		{exp: "n >= 1", want: nil},
+		{exp: "n * 2 != 2", want: nil}, // 无法分析
+		{exp: "n % 1000 == 1 ? 0 : 1", want: nil},
+	} {
+		exp, err := Compile(tt.exp, Extended())
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.exp, err)
+		}
+		if got := Identify(exp); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Identify(%q)=%q, want %q", tt.exp, got, tt.want)
+		}
+	}
+}
+
+func TestRuleRecognize(t *testing.T) {
+	for _, s := range []string{"(n != 1)", "n>1 ? 1 : 0", "n == 1 ? 0 : ( n >= 2 && n <= 4 ) ? 1 : 2"} {
+		r, err := NewRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.fn == nil {
+			t.Errorf("NewRule(%q) should use a native implementation", s)
+		}
+		for n := range int64(300) {
+			got, _ := r.Eval(n)
+			if want, _ := r.Expression().Eval(n); got != want {
+				t.Errorf("%q n=%d: got=%d, want=%d", s, n, got, want)
+			}
+		}
+	}
+	if r, _ := NewRule("n >= 1"); r.fn != nil {
+		t.Errorf("n >= 1 is not a common rule")
+	}
+}
+
+func TestShapeWithin(t *testing.T) {
+	for _, tt := range []struct {
+		s    shape
+		want bool
+	}{
+		{s: shape{lo: 0, hi: 100, period: 100}, want: true},
+		{s: shape{lo: 0, hi: 0, period: maxCompare / 2}, want: true},
+		// 周期超过一半时 maxCompare-2*period 不能下溢
+		{s: shape{lo: 0, hi: 0, period: maxCompare/2 + 1}, want: false},
+		{s: shape{lo: 0, hi: 0, period: 4000000}, want: false},
+		{s: shape{lo: 0, hi: maxCompare, period: 1}, want: false},
+	} {
+		if got := tt.s.comparable(); got != tt.want {
+			t.Errorf("%+v.comparable()=%v, want %v", tt.s, got, tt.want)
+		}
+	}
+	// 阈值或周期很大的规则不与已知规则比较
+	for _, s := range []string{"n % 4000000 == 1", "n % 2000000 == 1", "n > 2000000 ? 1 : 0"} {
+		exp, err := Compile(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fn := recognize(exp); fn != nil {
+			t.Errorf("recognize(%q) should give up", s)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package plurals
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+)
+
+// maxInferForms bounds the number of forms Infer accepts, it tries every
+// order of the forms in the ternary chain.
+const maxInferForms = 6
+
+// maxInferRuns bounds the runs of consecutive samples with the same form for
+// which Infer tries ranges of n, there are quadratically many of them.
+const maxInferRuns = 32
+
+// Infer finds the simplest expressions that select samples[n] for every n,
+// ranked by size, then by the order of the forms. Candidates are chains of
+// ternary expressions whose conditions compare n, n % 10 or n % 100 with
+// constants, ranges of them, or two of those joined with && or ||; a rule
+// with forms 0 and 1 may also be a single condition, like `n != 1`.
+// Ranges of n start and end where the form of the samples changes.
+func Infer(samples map[int64]int) ([]Expression, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples")
+	}
+	ns := slices.Sorted(maps.Keys(samples))
+	var forms []int
+	for _, n := range ns {
+		forms = append(forms, samples[n])
+	}
+	slices.Sort(forms)
+	forms = slices.Compact(forms)
+	if len(forms) == 1 {
+		return []Expression{Num(int64(forms[0]))}, nil
+	}
+	if len(forms) > maxInferForms {
+		return nil, fmt.Errorf("too many forms: %d, at most %d", len(forms), maxInferForms)
+	}
+
+	in := &inferer{atoms: inferAtoms(ns, samples), memo: map[string]*condition{}}
+	sets := map[int]bitset{}
+	for i, n := range ns {
+		if sets[samples[n]] == nil {
+			sets[samples[n]] = newBitset(len(ns))
+		}
+		sets[samples[n]].set(i)
+	}
+	var candidates []condition
+	if forms[0] == 0 && forms[1] == 1 && len(forms) == 2 {
+		// 条件本身的值就是形式
+		if c := in.search(sets[1], sets[0]); c != nil {
+			candidates = append(candidates, *c)
+		}
+	}
+	for order := range permutations(forms) {
+		last := len(order) - 1
+		conds := make([]*condition, last)
+		for i := range last {
+			rest := newBitset(len(ns))
+			for _, form := range order[i+1:] {
+				rest = rest.or(sets[form])
+			}
+			if conds[i] = in.search(sets[order[i]], rest); conds[i] == nil {
+				break
+			}
+		}
+		if slices.Contains(conds, nil) {
+			continue
+		}
+		exp := Num(int64(order[last]))
+		for i := last - 1; i >= 0; i-- {
+			exp = Cond(conds[i].exp, Num(int64(order[i])), exp)
+		}
+		candidates = append(candidates, condition{exp: exp, size: nodeCount(exp), order: order})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no expression found for the samples")
+	}
+	for i := range candidates {
+		candidates[i].key = fmt.Sprint(candidates[i].exp)
+	}
+	slices.SortStableFunc(candidates, func(a, b condition) int {
+		return cmp.Or(cmp.Compare(a.size, b.size), slices.Compare(a.order, b.order), cmp.Compare(a.key, b.key))
+	})
+	var out []Expression
+	for i, c := range candidates {
+		if i > 0 && c.key == candidates[i-1].key {
+			continue
+		}
+		for _, n := range ns {
+			if got, err := c.exp.Eval(n); err != nil || got != int64(samples[n]) {
+				return nil, fmt.Errorf("assert failed: %v at n=%d: got %d, want %d", c.exp, n, got, samples[n])
+			}
+		}
+		out = append(out, c.exp)
+	}
+	return out, nil
+}
+
+// condition is a candidate expression with the samples it is true for.
+type condition struct {
+	exp   Expression
+	size  int
+	vec   bitset
+	key   string
+	order []int // 三元表达式链中形式的顺序
+}
+
+type inferer struct {
+	atoms []condition // 按大小排序
+	memo  map[string]*condition
+}
+
+// search returns the smallest condition true for every sample in t and
+// false for every sample in f.
+func (in *inferer) search(t, f bitset) *condition {
+	key := string(t.bytes()) + "|" + string(f.bytes())
+	if c, ok := in.memo[key]; ok {
+		return c
+	}
+	var best *condition
+	better := func(exp Expression, size int) {
+		if best == nil || size < best.size {
+			best = &condition{exp: exp, size: size}
+		}
+	}
+	var covers, avoids []*condition // 可用于 && 与 || 的两侧
+	for i := range in.atoms {
+		a := &in.atoms[i]
+		cover, avoid := t.subset(a.vec), a.vec.disjoint(f)
+		if cover && avoid {
+			better(a.exp, a.size)
+		}
+		if cover {
+			covers = append(covers, a)
+		}
+		if avoid {
+			avoids = append(avoids, a)
+		}
+	}
+	// a && b: 两侧都覆盖 t, 交集避开 f; a || b: 两侧都避开 f, 并集覆盖 t
+	for i, a := range covers {
+		for _, b := range covers[i+1:] {
+			if best != nil && a.size+b.size+1 >= best.size {
+				break
+			}
+			if disjoint3(a.vec, b.vec, f) {
+				better(join("&&", a.exp, b.exp), a.size+b.size+1)
+			}
+		}
+	}
+	for i, a := range avoids {
+		for _, b := range avoids[i+1:] {
+			if best != nil && a.size+b.size+1 >= best.size {
+				break
+			}
+			if covered(t, a.vec, b.vec) {
+				better(join("||", a.exp, b.exp), a.size+b.size+1)
+			}
+		}
+	}
+	in.memo[key] = best
+	return best
+}
+
+// join 连接 a 与 b, 同一运算符不再加括号
+func join(op string, a, b Expression) Expression {
+	var exps []Expression
+	for _, exp := range []Expression{a, b} {
+		if l, ok := exp.(*LogicNode); ok && l.Op == op {
+			exps = append(exps, l.Exps...)
+		} else {
+			exps = append(exps, exp)
+		}
+	}
+	return logic(op, exps)
+}
+
+// inferAtoms 返回 n, n % 10, n % 100 与样例中出现的值比较的条件,
+// 真值相同的只保留最小的一个
+func inferAtoms(ns []int64, samples map[int64]int) []condition {
+	// n 的比较与范围只在形式变化处开始或结束, 否则范围的个数是样例数的平方
+	starts, ends := map[int64]bool{}, map[int64]bool{}
+	for i, n := range ns {
+		if i == 0 || samples[ns[i-1]] != samples[n] {
+			starts[n] = true
+		}
+		if i == len(ns)-1 || samples[ns[i+1]] != samples[n] {
+			ends[n] = true
+		}
+	}
+	var atoms []condition
+	seen := map[string]int{}
+	values := make([]int64, len(ns))
+	// 同一形状的条件大小相同, 表达式在去重后才构造
+	add := func(size int, truth func(v int64) bool, build func() Expression) {
+		vec := newBitset(len(values))
+		for i, v := range values {
+			if truth(v) {
+				vec.set(i)
+			}
+		}
+		key := string(vec.bytes())
+		if i, ok := seen[key]; ok {
+			if atoms[i].size <= size {
+				return
+			}
+			atoms[i] = condition{exp: build(), size: size, vec: vec}
+			return
+		}
+		seen[key] = len(atoms)
+		atoms = append(atoms, condition{exp: build(), size: size, vec: vec})
+	}
+	for _, mod := range []int64{0, 10, 100} {
+		x := N()
+		if mod > 0 {
+			x = Mod(N(), Num(mod))
+		}
+		for i, n := range ns {
+			values[i] = n
+			if mod > 0 {
+				values[i] = n % mod
+			}
+		}
+		compare := nodeCount(Eq(x, Num(0)))
+		cs := slices.Compact(slices.Sorted(slices.Values(values)))
+		for i, c := range cs {
+			add(compare, func(v int64) bool { return v == c }, func() Expression { return Eq(x, Num(c)) })
+			add(compare, func(v int64) bool { return v != c }, func() Expression { return Ne(x, Num(c)) })
+			if i > 0 && (mod > 0 || starts[c]) {
+				add(compare, func(v int64) bool { return v >= c }, func() Expression { return Ge(x, Num(c)) })
+			}
+			if i < len(cs)-1 && (mod > 0 || ends[c]) {
+				add(compare, func(v int64) bool { return v <= c }, func() Expression { return Le(x, Num(c)) })
+			}
+			if i == 0 || i == len(cs)-1 {
+				continue // 范围的一端是最值时已是单个比较
+			}
+			if mod == 0 && (!starts[c] || len(starts) > maxInferRuns) {
+				continue
+			}
+			for _, hi := range cs[i+1 : len(cs)-1] {
+				if mod == 0 && !ends[hi] {
+					continue
+				}
+				lo := c
+				add(2*compare+1, func(v int64) bool { return v >= lo && v <= hi },
+					func() Expression { return And(Ge(x, Num(lo)), Le(x, Num(hi))) })
+				add(2*compare+1, func(v int64) bool { return v < lo || v > hi },
+					func() Expression { return Or(Lt(x, Num(lo)), Gt(x, Num(hi))) })
+			}
+		}
+	}
+	slices.SortStableFunc(atoms, func(a, b condition) int { return cmp.Compare(a.size, b.size) })
+	return atoms
+}
+
+// nodeCount 返回语法树的节点数, 不计包装节点与括号
+func nodeCount(exp Expression) int {
+	count := 1
+	for _, kid := range view(exp).kids {
+		count += nodeCount(kid)
+	}
+	return count
+}
+
+// permutations yields every order of list.
+func permutations(list []int) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		order := slices.Clone(list)
+		var permute func(k int) bool
+		permute = func(k int) bool {
+			if k == len(order) {
+				return yield(slices.Clone(order))
+			}
+			for i := k; i < len(order); i++ {
+				order[k], order[i] = order[i], order[k]
+				if !permute(k + 1) {
+					return false
+				}
+				order[k], order[i] = order[i], order[k]
+			}
+			return true
+		}
+		permute(0)
+	}
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset { return make(bitset, (n+63)/64) }
+
+func (b bitset) set(i int) { b[i/64] |= 1 << (i % 64) }
+
+// disjoint3 reports whether a, b and c have no common element.
+func disjoint3(a, b, c bitset) bool {
+	for i := range a {
+		if a[i]&b[i]&c[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// covered reports whether t is a subset of the union of a and b.
+func covered(t, a, b bitset) bool {
+	for i := range t {
+		if t[i]&^(a[i]|b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) or(o bitset) bitset {
+	out := make(bitset, len(b))
+	for i := range b {
+		out[i] = b[i] | o[i]
+	}
+	return out
+}
+
+// subset reports whether b is a subset of o.
+func (b bitset) subset(o bitset) bool {
+	for i := range b {
+		if b[i]&^o[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) disjoint(o bitset) bool {
+	for i := range b {
+		if b[i]&o[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) bytes() []byte {
+	out := make([]byte, 0, len(b)*8)
+	for _, w := range b {
+		for s := 0; s < 64; s += 8 {
+			out = append(out, byte(w>>s))
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package plurals
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	for _, tt := range []struct {
+		exp  string // 用于生成样例
+		want string
+	}{
+		{exp: "0", want: "0"},
+		{exp: "n != 1", want: "n != 1"},
+		{exp: "n > 1", want: "n >= 2"},
+		{exp: "n == 1 ? 0 : n == 2 ? 1 : 2", want: "n == 1 ? 0 : n == 2 ? 1 : 2"},
+		{exp: commonRules[3].exp, want: "n % 10 == 1 && n % 100 != 11 ? 0 : n != 0 ? 1 : 2"},                                   // Latvian
+		{exp: commonRules[7].exp, want: "n % 10 < 1 || n % 10 > 4 || n % 100 >= 5 && n % 100 <= 14 ? 2 : n % 10 == 1 ? 0 : 1"}, // Russian
+		{exp: commonRules[11].exp, want: "n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n % 100 >= 11 ? 4 : n % 100 >= 3 ? 3 : 5"},    // Arabic
+	} {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := map[int64]int{}
+		for n := range int64(120) {
+			val, _ := exp.Eval(n)
+			samples[n] = int(val)
+		}
+		got, err := Infer(samples)
+		if err != nil {
+			t.Errorf("Infer(%q) err=%v", tt.exp, err)
+			continue
+		}
+		if fmt.Sprint(got[0]) != tt.want {
+			t.Errorf("Infer(%q)=%v, want %v", tt.exp, got[0], tt.want)
+		}
+		for i, c := range got {
+			if i > 0 && nodeCount(c) < nodeCount(got[i-1]) {
+				t.Errorf("Infer(%q): %v is larger than %v", tt.exp, got[i-1], c)
+			}
+			for n, form := range samples {
+				if val, err := c.Eval(n); err != nil || val != int64(form) {
+					t.Errorf("Infer(%q): %v at n=%d = %d, %v, want %d", tt.exp, c, n, val, err, form)
+				}
+			}
+		}
+	}
+}
+
+func TestInferError(t *testing.T) {
+	for _, samples := range []map[int64]int{
+		nil,
+		{0: 0, 1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 6: 6},
+	} {
+		if got, err := Infer(samples); err == nil {
+			t.Errorf("Infer(%v)=%v, want error", samples, got)
+		}
+	}
+}
+
+func TestInferLarge(t *testing.T) {
+	// 语言学资料中常见的 0..1000 的样例
+	exp, err := Compile(commonRules[7].exp) // Russian
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := map[int64]int{}
+	for n := range int64(1001) {
+		val, _ := exp.Eval(n)
+		samples[n] = int(val)
+	}
+	got, err := Infer(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "n % 10 < 1 || n % 10 > 4 || n % 100 >= 5 && n % 100 <= 14 ? 2 : n % 10 == 1 ? 0 : 1"; fmt.Sprint(got[0]) != want {
+		t.Errorf("Infer=%v, want %v", got[0], want)
+	}
+}
@@ -0,0 +1,31 @@
+package plurals
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Integer is the set of Go integer types accepted by EvalInt.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// EvalInt evaluates exp with a count of any integer type.
+// Every value is evaluated as the number it represents: unsigned values above
+// math.MaxInt64 are not wrapped or saturated but evaluated exactly by EvalBig,
+// and an error is returned if the result does not fit in int64.
+// Use EvalGNU for the unsigned arithmetic of GNU libintl.
+func EvalInt[T Integer](exp Expression, n T) (int64, error) {
+	if i := int64(n); n < 0 || i >= 0 {
+		return exp.Eval(i)
+	}
+	val, err := EvalBig(exp, new(big.Int).SetUint64(uint64(n)))
+	if err != nil {
+		return 0, err
+	}
+	if !val.IsInt64() {
+		return 0, fmt.Errorf("result out of int64 range: %v", val)
+	}
+	return val.Int64(), nil
+}
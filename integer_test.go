@@ -0,0 +1,43 @@
+package plurals
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEvalInt(t *testing.T) {
+	russian, _ := Compile("n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2")
+	check := func(name string, got, want int64, err error) {
+		t.Helper()
+		if err != nil || got != want {
+			t.Errorf("%s: got=%v, err=%v, want=%v", name, got, err, want)
+		}
+	}
+	got, err := EvalInt(russian, 21)
+	check("int", got, 0, err)
+	// -22 % 10 == -2, same as the int64 path
+	got, err = EvalInt(russian, int8(-22))
+	check("int8", got, 2, err)
+	got, err = EvalInt(russian, int32(111))
+	check("int32", got, 2, err)
+	got, err = EvalInt(russian, uint(3))
+	check("uint", got, 1, err)
+	got, err = EvalInt(russian, uint8(255))
+	check("uint8", got, 2, err)
+	// 18446744073709551615 % 100 == 15
+	got, err = EvalInt(russian, uint64(math.MaxUint64))
+	check("uint64 max", got, 2, err)
+	// 9223372036854775821 % 100 == 21
+	got, err = EvalInt(russian, uint64(math.MaxInt64)+14)
+	check("uint64 above int64", got, 0, err)
+	type count uint64
+	got, err = EvalInt(russian, count(math.MaxInt64))
+	check("named uint64", got, 2, err)
+
+	identity, _ := Compile("n")
+	if got, err := EvalInt(identity, uint64(math.MaxUint64)); err == nil {
+		t.Errorf("want out of range error, got %v", got)
+	}
+	got, err = EvalInt(identity, int64(math.MinInt64))
+	check("int64 min", got, math.MinInt64, err)
+}
@@ -1,15 +1,26 @@
 package plurals
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func Lex(s string) (tokens []Token, err error) {
-	var (
-		pos   = 0
-		siz   = len(s)
-		token Token
-	)
+	return DefaultCompileOptions.Lex(s)
+}
+
+func (o CompileOptions) Lex(s string) (tokens []Token, err error) {
+	if o.MaxLength > 0 && len(s) > o.MaxLength {
+		return nil, &LimitError{Limit: "length", Max: o.MaxLength}
+	}
+	l := Lexer{opts: o, s: s, line: 1}
+	size := estimate(s)
+	if o.MaxTokens > 0 {
+		size = min(size, o.MaxTokens)
+	}
+	tokens = make([]Token, 0, size)
 	for {
-		token, pos = readToken(s, pos, siz)
+		token := l.Next()
 		switch token.Type {
 		case TokenTypeEOF:
 			return
@@ -17,38 +28,159 @@ func Lex(s string) (tokens []Token, err error) {
 			err = fmt.Errorf("error read token: %s, input: %q", token.Value, s)
 			return
 		}
+		if o.MaxTokens > 0 && len(tokens) >= o.MaxTokens {
+			return nil, &LimitError{Limit: "tokens", Max: o.MaxTokens}
+		}
 		tokens = append(tokens, token)
 	}
 }
 
-func readToken(s string, pos, siz int) (token Token, newPos int) {
+// intSuffix reports whether s is a C integer suffix: u or U, l, ll, L or LL,
+// or one of each in either order.
+func intSuffix(s string) bool {
+	long := func(s string) bool { return s == "l" || s == "ll" || s == "L" || s == "LL" }
+	switch {
+	case s == "u" || s == "U" || long(s):
+		return true
+	case s[0] == 'u' || s[0] == 'U':
+		return long(s[1:])
+	case s[len(s)-1] == 'u' || s[len(s)-1] == 'U':
+		return long(s[:len(s)-1])
+	}
+	return false
+}
+
+// estimate 返回 token 数的上限: 非空白字节数
+func estimate(s string) (count int) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' && s[i] != '\t' {
+			count++
+		}
+	}
+	return
+}
+
+// Lexer reads the tokens of an expression one at a time.
+// After an ERR token, reading continues from the next character.
+type Lexer struct {
+	opts   CompileOptions
+	s      string
+	pos    int
+	peek   Token
+	peeked bool
+	// 行号统计到 s[:lineAt]
+	line, lineAt, lineStart int
+}
+
+// NewLexer returns a Lexer for s, Lenient, WithWarning and KeepWhitespace apply.
+func NewLexer(s string, opts ...Option) *Lexer {
+	o := DefaultCompileOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.lexer(s)
+}
+
+func (o CompileOptions) lexer(s string) *Lexer {
+	return &Lexer{opts: o, s: s, line: 1}
+}
+
+// Next returns the next token, at the end of input it keeps returning EOF.
+func (l *Lexer) Next() Token {
+	if l.peeked {
+		l.peeked = false
+		return l.peek
+	}
+	var token Token
+	token, l.pos = l.opts.readToken(l.s, l.pos, len(l.s))
+	token.Line, token.Column = l.position(token.Start)
+	return token
+}
+
+// Peek returns the next token without consuming it.
+func (l *Lexer) Peek() Token {
+	if !l.peeked {
+		l.peek = l.Next()
+		l.peeked = true
+	}
+	return l.peek
+}
+
+// position 返回偏移 offset 处的行号、列号, 从 1 开始
+func (l *Lexer) position(offset int) (line, column int) {
+	for ; l.lineAt < offset; l.lineAt++ {
+		// \n, \r\n 与单独的 \r 都是换行
+		if ch := l.s[l.lineAt]; ch == '\n' || ch == '\r' && !strings.HasPrefix(l.s[l.lineAt+1:], "\n") {
+			l.line++
+			l.lineStart = l.lineAt + 1
+		}
+	}
+	return l.line, offset - l.lineStart + 1
+}
+
+func (o CompileOptions) readToken(s string, pos, siz int) (token Token, newPos int) {
 	// 跳过空白字符
-	var ch byte
-	for {
-		if pos >= siz {
-			return Token{Type: TokenTypeEOF}, siz
+	start := pos
+	for pos < siz {
+		if s[pos] == ' ' || s[pos] == '\t' {
+			pos++
+			continue
 		}
-		ch = s[pos]
-		pos++
-		if ch != ' ' && ch != '\t' {
-			break
+		if n, what := newline(s, pos); o.Lenient && n > 0 {
+			o.warn(pos, pos+n, what)
+			pos += n
+			continue
 		}
+		break
+	}
+	if o.Whitespace && pos > start {
+		return Token{
+			Type:  TokenTypeWSP,
+			Value: s[start:pos],
+			Start: start,
+			End:   pos,
+		}, pos
+	}
+	if pos >= siz {
+		return Token{Type: TokenTypeEOF, Start: siz, End: siz}, siz
+	}
+	// Value 直接截取输入, 不再分配内存
+	start = pos
+	ch := s[pos]
+	pos++
+	if o.Extended && isLetter(ch) {
+		return o.readWord(s, start, siz)
 	}
-	val := []byte{ch}
 	switch ch {
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		start := pos - 1
 		num := int64(ch - '0')
 		for pos < siz && s[pos] >= '0' && s[pos] <= '9' {
 			ch = s[pos]
 			pos++
-			val = append(val, ch)
 			num *= 10
 			num += int64(ch - '0')
 		}
+		if o.Lenient {
+			// C 语言的整数后缀 1U 1L 1UL
+			suffix := pos
+			for pos < siz && strings.IndexByte("uUlL", s[pos]) >= 0 {
+				pos++
+			}
+			if pos > suffix && !intSuffix(s[suffix:pos]) {
+				return Token{
+					Type:  TokenTypeERR,
+					Value: fmt.Sprintf("at column [%d:%d]: invalid integer suffix %q", suffix, pos, s[suffix:pos]),
+					Start: suffix,
+					End:   pos,
+				}, pos
+			}
+			if pos > suffix {
+				o.warn(suffix, pos, "integer suffix")
+			}
+		}
 		return Token{
 			Type:   TokenTypeNUM,
-			Value:  string(val),
+			Value:  s[start:pos],
 			Number: num,
 			Start:  start,
 			End:    pos,
@@ -58,24 +190,23 @@ func readToken(s string, pos, siz int) (token Token, newPos int) {
 			pos++
 			return Token{
 				Type:  TokenTypeEQU,
-				Value: "!=",
+				Value: s[start:pos],
 				Start: pos - 2,
 				End:   pos,
 			}, pos
 		}
 		return Token{
 			Type:  TokenTypeLGC,
-			Value: "!",
+			Value: s[start:pos],
 			Start: pos - 1,
 			End:   pos,
 		}, pos
 	case '&', '|', '=':
 		if pos < siz && s[pos] == ch {
 			pos++
-			val = append(val, ch)
 			return Token{
 				Type:  ch2Typ[ch],
-				Value: string(val),
+				Value: s[start:pos],
 				Start: pos - 2,
 				End:   pos,
 			}, pos
@@ -84,39 +215,133 @@ func readToken(s string, pos, siz int) (token Token, newPos int) {
 			Type: TokenTypeERR,
 			Value: fmt.Sprintf("at column [%d:%d]: expected '%c%c'",
 				pos-1, pos, ch, ch),
+			Start: pos - 1,
+			End:   pos,
 		}, pos
 	case '<', '>':
 		if pos < siz && s[pos] == '=' {
 			ch = s[pos]
 			pos++
-			val = append(val, ch)
 			return Token{
 				Type:  TokenTypeCMP,
-				Value: string(val),
+				Value: s[start:pos],
 				Start: pos - 2,
 				End:   pos,
 			}, pos
 		}
 		return Token{
 			Type:  TokenTypeCMP,
-			Value: string(val),
+			Value: s[start:pos],
+			Start: pos - 1,
+			End:   pos,
+		}, pos
+	case 'N':
+		if !o.Lenient {
+			break
+		}
+		o.warn(pos-1, pos, "uppercase variable N")
+		return Token{
+			Type:  TokenTypeIDN,
+			Value: s[start:pos],
 			Start: pos - 1,
 			End:   pos,
 		}, pos
+	case '.':
+		if o.Extended && pos < siz && s[pos] == '.' {
+			pos++
+			return Token{
+				Type:  TokenTypeRNG,
+				Value: s[start:pos],
+				Start: start,
+				End:   pos,
+			}, pos
+		}
+	case ',':
+		if o.Extended {
+			return Token{
+				Type:  TokenTypeSEP,
+				Value: s[start:pos],
+				Start: start,
+				End:   pos,
+			}, pos
+		}
 	case '*', '/', '%', '+', '-', '?', ':', 'n', '(', ')', ';':
 		return Token{
 			Type:  ch2Typ[ch],
-			Value: string(val),
+			Value: s[start:pos],
 			Start: pos - 1,
 			End:   pos,
 		}, pos
-	default:
+	}
+	return Token{
+		Type: TokenTypeERR,
+		Value: fmt.Sprintf("at column [%d:%d] unexpected '%c'",
+			pos-1, pos, ch),
+		Start: pos - 1,
+		End:   pos,
+	}, pos
+}
+
+func isLetter(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+}
+
+func wordEnd(s string, pos int) int {
+	for pos < len(s) && (isLetter(s[pos]) || s[pos] >= '0' && s[pos] <= '9') {
+		pos++
+	}
+	return pos
+}
+
+// readWord 读取扩展语法中的单词: in, not in, 以及变量名
+func (o CompileOptions) readWord(s string, start, siz int) (token Token, newPos int) {
+	pos := wordEnd(s, start)
+	switch word := s[start:pos]; word {
+	case "in":
+		return Token{Type: TokenTypeKWD, Value: word, Start: start, End: pos}, pos
+	case "not":
+		next := pos
+		for next < siz && (s[next] == ' ' || s[next] == '\t') {
+			next++
+		}
+		if end := wordEnd(s, next); s[next:end] == "in" {
+			return Token{Type: TokenTypeKWD, Value: "not in", Start: start, End: end}, end
+		}
 		return Token{
-			Type: TokenTypeERR,
-			Value: fmt.Sprintf("at column [%d:%d] unexpected '%c'",
-				pos-1, pos, ch),
+			Type:  TokenTypeERR,
+			Value: fmt.Sprintf("at column [%d:%d]: expected 'in' after 'not'", start, pos),
+			Start: start,
+			End:   pos,
 		}, pos
 	}
+	if s[start:pos] == "N" {
+		// 与标准语法一致, N 只在宽松模式下表示 n
+		if !o.Lenient {
+			return Token{
+				Type:  TokenTypeERR,
+				Value: fmt.Sprintf("at column [%d:%d]: uppercase variable N", start, pos),
+				Start: start,
+				End:   pos,
+			}, pos
+		}
+		o.warn(start, pos, "uppercase variable N")
+	}
+	return Token{Type: TokenTypeIDN, Value: s[start:pos], Start: start, End: pos}, pos
+}
+
+// newline 返回 s[i:] 开头的换行(或反斜杠续行)长度
+func newline(s string, i int) (int, string) {
+	switch {
+	case strings.HasPrefix(s[i:], "\\\r\n"):
+		return 3, "backslash-newline"
+	case strings.HasPrefix(s[i:], "\\\n"):
+		return 2, "backslash-newline"
+	case strings.HasPrefix(s[i:], "\r\n"):
+		return 2, "newline"
+	case s[i] == '\n' || s[i] == '\r':
+		return 1, "newline"
+	}
+	return 0, ""
 }
 
 var ch2Typ = map[byte]TokenType{
@@ -1,6 +1,7 @@
 package plurals
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -55,3 +56,134 @@ func TestLex(t *testing.T) {
 	}
 
 }
+
+func TestLexer(t *testing.T) {
+	const s = "n % 10 == 1\n? 0 & 1\n: 2"
+	for _, tt := range []struct {
+		opts []Option
+		want []string
+	}{
+		{opts: []Option{Lenient()}, want: []string{
+			`1:1 [0:1] IDN "n"`,
+			`1:3 [2:3] MUL "%"`,
+			`1:5 [4:6] NUM "10"`,
+			`1:8 [7:9] EQU "=="`,
+			`1:11 [10:11] NUM "1"`,
+			`2:1 [12:13] QST "?"`,
+			`2:3 [14:15] NUM "0"`,
+			`2:5 [16:17] ERR "at column [16:17]: expected '&&'"`,
+			`2:7 [18:19] NUM "1"`,
+			`3:1 [20:21] COL ":"`,
+			`3:3 [22:23] NUM "2"`,
+		}},
+		{opts: []Option{Lenient(), KeepWhitespace()}, want: []string{
+			`1:1 [0:1] IDN "n"`,
+			`1:2 [1:2] WSP " "`,
+			`1:3 [2:3] MUL "%"`,
+			`1:4 [3:4] WSP " "`,
+			`1:5 [4:6] NUM "10"`,
+			`1:7 [6:7] WSP " "`,
+			`1:8 [7:9] EQU "=="`,
+			`1:10 [9:10] WSP " "`,
+			`1:11 [10:11] NUM "1"`,
+			`1:12 [11:12] WSP "\n"`,
+			`2:1 [12:13] QST "?"`,
+			`2:2 [13:14] WSP " "`,
+			`2:3 [14:15] NUM "0"`,
+			`2:4 [15:16] WSP " "`,
+			`2:5 [16:17] ERR "at column [16:17]: expected '&&'"`,
+			`2:6 [17:18] WSP " "`,
+			`2:7 [18:19] NUM "1"`,
+			`2:8 [19:20] WSP "\n"`,
+			`3:1 [20:21] COL ":"`,
+			`3:2 [21:22] WSP " "`,
+			`3:3 [22:23] NUM "2"`,
+		}},
+		{opts: nil, want: []string{
+			`1:1 [0:1] IDN "n"`,
+			`1:3 [2:3] MUL "%"`,
+			`1:5 [4:6] NUM "10"`,
+			`1:8 [7:9] EQU "=="`,
+			`1:11 [10:11] NUM "1"`,
+			`1:12 [11:12] ERR "at column [11:12] unexpected '\n'"`,
+			`2:1 [12:13] QST "?"`,
+			`2:3 [14:15] NUM "0"`,
+			`2:5 [16:17] ERR "at column [16:17]: expected '&&'"`,
+			`2:7 [18:19] NUM "1"`,
+			`2:8 [19:20] ERR "at column [19:20] unexpected '\n'"`,
+			`3:1 [20:21] COL ":"`,
+			`3:3 [22:23] NUM "2"`,
+		}},
+	} {
+		l := NewLexer(s, tt.opts...)
+		var got []string
+		for {
+			peek := l.Peek()
+			token := l.Next()
+			if peek != token {
+				t.Fatalf("peek=%v, next=%v", peek, token)
+			}
+			if token.Type == TokenTypeEOF {
+				break
+			}
+			got = append(got, fmt.Sprintf("%d:%d [%d:%d] %s %q",
+				token.Line, token.Column, token.Start, token.End, token.Type, token.Value))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("got=%q\nwant=%q", got, tt.want)
+		}
+		if token := l.Next(); token.Type != TokenTypeEOF || token.Start != len(s) {
+			t.Errorf("want EOF at end, got %v", token)
+		}
+	}
+}
+
+func TestLexerLineEndings(t *testing.T) {
+	for _, s := range []string{"n\n!=\n1", "n\r!=\r1", "n\r\n!=\r\n1"} {
+		l := NewLexer(s, Lenient())
+		var got []string
+		for token := l.Next(); token.Type != TokenTypeEOF; token = l.Next() {
+			got = append(got, fmt.Sprintf("%d:%d %s", token.Line, token.Column, token.Value))
+		}
+		if want := []string{"1:1 n", "2:1 !=", "3:1 1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got=%q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestLexAllocs(t *testing.T) {
+	for _, s := range []string{
+		"n != 1",
+		"n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2",
+		"n==0?0:n==1?1:n==2?2:n%100>=3&&n%100<=10?3:n%100>=11?4:5",
+	} {
+		// 只有 tokens 切片本身
+		if allocs := testing.AllocsPerRun(100, func() { Lex(s) }); allocs != 1 {
+			t.Errorf("Lex(%q) allocs=%v, want 1", s, allocs)
+		}
+		l := NewLexer(s)
+		if allocs := testing.AllocsPerRun(100, func() {
+			*l = Lexer{opts: l.opts, s: s, line: 1}
+			for l.Next().Type != TokenTypeEOF {
+			}
+		}); allocs != 0 {
+			t.Errorf("Lexer(%q) allocs=%v, want 0", s, allocs)
+		}
+	}
+}
+
+func BenchmarkLex(b *testing.B) {
+	s := "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2"
+	b.ReportAllocs()
+	for range b.N {
+		Lex(s)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	s := "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2"
+	b.ReportAllocs()
+	for range b.N {
+		Compile(s)
+	}
+}
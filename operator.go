@@ -0,0 +1,165 @@
+package plurals
+
+type assoc int
+
+const (
+	assocLeft  assoc = iota // a op b op c => (a op b) op c
+	assocRight              // a ? b : c ? d : e => a ? b : (c ? d : e), 仅用于前缀与三元运算符
+	assocList               // a op b op c => 一个节点包含 a, b, c, 只有 a 时也会创建
+)
+
+// operator is an entry of the operator table that drives the parser.
+type operator struct {
+	value string // token 值
+	close string // 三元运算符的第二个符号
+	prec  int    // 优先级, 越大越优先
+	assoc assoc
+	arity int // 1 前缀, 2 二元, 3 三元
+	// build 创建语法树节点, ops 为 operands 之间的运算符.
+	// assocList 与前缀运算符在没有运算符时也会调用, 用于创建包装节点,
+	// 此时使用该优先级第一个运算符的 build
+	build buildFunc
+	// parse 不为空时由它解析右侧并创建节点, 用于右侧不是表达式的运算符, 仅支持 assocLeft
+	parse func(p *parser, idx int, left Expression, op string) (int, Expression, error)
+}
+
+type buildFunc func(operands []Expression, ops []string) Expression
+
+// operators 对应 token.go 中的产生式
+var operators = []operator{
+	{value: "?", close: ":", prec: 0, assoc: assocRight, arity: 3, build: buildTernary},
+	{value: "||", prec: 1, assoc: assocList, arity: 2, build: buildLogic("||")},
+	{value: "&&", prec: 2, assoc: assocList, arity: 2, build: buildLogic("&&")},
+	{value: "==", prec: 3, assoc: assocLeft, arity: 2, build: buildCompare},
+	{value: "!=", prec: 3, assoc: assocLeft, arity: 2, build: buildCompare},
+	{value: ">", prec: 4, assoc: assocLeft, arity: 2, build: buildCompare},
+	{value: ">=", prec: 4, assoc: assocLeft, arity: 2, build: buildCompare},
+	{value: "<", prec: 4, assoc: assocLeft, arity: 2, build: buildCompare},
+	{value: "<=", prec: 4, assoc: assocLeft, arity: 2, build: buildCompare},
+	{value: "in", prec: 4, assoc: assocLeft, arity: 2, parse: parseIn},
+	{value: "not in", prec: 4, assoc: assocLeft, arity: 2, parse: parseIn},
+	{value: "+", prec: 5, assoc: assocList, arity: 2, build: buildBinary},
+	{value: "-", prec: 5, assoc: assocList, arity: 2, build: buildBinary},
+	{value: "*", prec: 6, assoc: assocList, arity: 2, build: buildBinary},
+	{value: "/", prec: 6, assoc: assocList, arity: 2, build: buildBinary},
+	{value: "%", prec: 6, assoc: assocList, arity: 2, build: buildBinary},
+	{value: "!", prec: 7, assoc: assocRight, arity: 1, build: buildUnary},
+}
+
+// level is the operators of the same precedence.
+type level struct {
+	ops   []*operator
+	assoc assoc
+	arity int
+	build buildFunc
+}
+
+var levels = buildLevels(operators)
+
+// buildLevels 按优先级分组, 同一优先级的运算符结合性、元数必须相同
+func buildLevels(operators []operator) []*level {
+	var levels []*level
+	for i := range operators {
+		op := &operators[i]
+		for len(levels) <= op.prec {
+			levels = append(levels, nil)
+		}
+		lv := levels[op.prec]
+		if lv == nil {
+			lv = &level{assoc: op.assoc, arity: op.arity, build: op.build}
+			levels[op.prec] = lv
+		}
+		if op.arity == 2 && op.assoc == assocRight {
+			panic("plurals: binary operators cannot be right-associative: " + op.value)
+		}
+		if lv.assoc != op.assoc || lv.arity != op.arity {
+			panic("plurals: operators of the same precedence must have the same associativity and arity: " + op.value)
+		}
+		lv.ops = append(lv.ops, op)
+	}
+	return levels
+}
+
+func (lv *level) match(token Token) *operator {
+	if token.Type == TokenTypeNUM || token.Type == TokenTypeIDN {
+		return nil
+	}
+	for _, op := range lv.ops {
+		if op.value == token.Value {
+			return op
+		}
+	}
+	return nil
+}
+
+func buildTernary(operands []Expression, _ []string) Expression {
+	return &TernaryNode{
+		Condition:   operands[0],
+		BranchTrue:  operands[1],
+		BranchFalse: operands[2],
+	}
+}
+
+func buildLogic(op string) buildFunc {
+	return func(operands []Expression, _ []string) Expression {
+		return &LogicNode{Op: op, Exps: operands}
+	}
+}
+
+func buildCompare(operands []Expression, ops []string) Expression {
+	return &CompareNode{Exp: operands[0], Op: ops[0], Other: operands[1]}
+}
+
+func buildBinary(operands []Expression, ops []string) Expression {
+	node := &BinaryNExp{Exp: operands[0], Op: ops}
+	if len(operands) > 1 {
+		node.Other = operands[1:]
+	}
+	return node
+}
+
+func buildUnary(operands []Expression, ops []string) Expression {
+	if len(ops) == 0 {
+		return &UnaryExp{Op: "", Exp: operands[0]}
+	}
+	return &UnaryExp{Op: ops[0], Exp: operands[0]}
+}
+
+// precedence 返回 exp 的优先级, 括号与数字、变量最高
+func precedence(exp Expression) int {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		return precOf["?"]
+	case *LogicNode:
+		if len(e.Exps) == 1 {
+			return precedence(e.Exps[0])
+		}
+		return precOf[e.Op]
+	case *CompareNode:
+		if e.Other == nil {
+			return precedence(e.Exp)
+		}
+		return precOf[e.Op]
+	case *InNode:
+		return precOf["in"]
+	case *BinaryNExp:
+		if len(e.Op) == 0 {
+			return precedence(e.Exp)
+		}
+		return precOf[e.Op[0]]
+	case *UnaryExp:
+		if e.Op == "" {
+			return precedence(e.Exp)
+		}
+		return precOf[e.Op]
+	}
+	return len(levels)
+}
+
+var precOf = func() map[string]int {
+	m := make(map[string]int, len(operators))
+	for _, op := range operators {
+		m[op.value] = op.prec
+	}
+	return m
+}()
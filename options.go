@@ -0,0 +1,111 @@
+package plurals
+
+import "fmt"
+
+// CompileOptions controls how an expression is compiled.
+// A limit <= 0 means no limit.
+type CompileOptions struct {
+	MaxLength int // 输入字节数
+	MaxTokens int // token 数
+	MaxDepth  int // 括号、三元表达式的嵌套深度
+	MaxNodes  int // 语法树节点数
+
+	// Lenient accepts common deviations from the GNU grammar found in real
+	// PO files: newlines (CRLF, backslash-newline), `N` and C integer suffixes.
+	Lenient bool
+	// OnWarning is called for each deviation tolerated in lenient mode.
+	OnWarning func(Warning)
+	// Whitespace makes the lexer emit WSP tokens, it is ignored by Compile.
+	Whitespace bool
+	// Extended enables the extension syntax that is not part of GNU gettext:
+	// `n % 10 in 2..4`, `n % 100 not in 12..14, 20`, and variables other
+	// than n, e.g. `n == 1 && m > 1`, evaluated with EvalEnv.
+	// Use GNUString to print an extended expression in standard syntax.
+	Extended bool
+}
+
+// DefaultCompileOptions is used by Compile and Lex. It is generous for any
+// real Plural-Forms header while safe for untrusted input.
+var DefaultCompileOptions = CompileOptions{
+	MaxLength: 4096,
+	MaxTokens: 1024,
+	MaxDepth:  100,
+	MaxNodes:  8192,
+}
+
+// NoLimits opts out of all limits, only use it for trusted input.
+var NoLimits = CompileOptions{}
+
+// LimitError is returned when an input exceeds one of the CompileOptions limits.
+type LimitError struct {
+	Limit string // length, tokens, depth, nodes
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("expression exceeds the %s limit of %d", e.Limit, e.Max)
+}
+
+// Warning reports something tolerated in lenient mode, at input [Start:End].
+type Warning struct {
+	Start, End int
+	Message    string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("at column [%d:%d]: %s", w.Start, w.End, w.Message)
+}
+
+func (o CompileOptions) warn(start, end int, msg string) {
+	if o.OnWarning != nil {
+		o.OnWarning(Warning{Start: start, End: end, Message: msg})
+	}
+}
+
+// Option configures Compile.
+type Option func(*CompileOptions)
+
+// Strict only accepts the grammar documented in token.go, this is the default.
+func Strict() Option {
+	return func(o *CompileOptions) { o.Lenient = false }
+}
+
+// Lenient accepts common variations, see CompileOptions.Lenient.
+func Lenient() Option {
+	return func(o *CompileOptions) { o.Lenient = true }
+}
+
+// WithWarning sets the callback for what lenient mode tolerated.
+func WithWarning(fn func(Warning)) Option {
+	return func(o *CompileOptions) { o.OnWarning = fn }
+}
+
+// Extended enables the extension syntax, see CompileOptions.Extended.
+func Extended() Option {
+	return func(o *CompileOptions) { o.Extended = true }
+}
+
+// KeepWhitespace makes CompileOptions.Lex and NewLexer emit whitespace as
+// WSP tokens. The package level Lex never does.
+func KeepWhitespace() Option {
+	return func(o *CompileOptions) { o.Whitespace = true }
+}
+
+// WithLimits replaces the resource limits, e.g. WithLimits(NoLimits) for trusted input.
+func WithLimits(limits CompileOptions) Option {
+	return func(o *CompileOptions) {
+		o.MaxLength = limits.MaxLength
+		o.MaxTokens = limits.MaxTokens
+		o.MaxDepth = limits.MaxDepth
+		o.MaxNodes = limits.MaxNodes
+	}
+}
+
+func (o CompileOptions) Compile(s string) (Expression, error) {
+	o.Whitespace = false
+	tokens, err := o.Lex(s)
+	if err != nil {
+		return nil, err
+	}
+	return o.parse(tokens)
+}
@@ -0,0 +1,104 @@
+package plurals
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileLimits(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + "n" + strings.Repeat(")", depth)
+	}
+	for _, tt := range []struct {
+		name  string
+		opts  CompileOptions
+		s     string
+		limit string
+	}{
+		{name: "deep", opts: DefaultCompileOptions, s: nested(50000), limit: "length"},
+		{name: "deep", opts: CompileOptions{MaxDepth: 100}, s: nested(50000), limit: "depth"},
+		{name: "ok", opts: DefaultCompileOptions, s: nested(90)},
+		{name: "trusted", opts: NoLimits, s: nested(5000)},
+		{name: "long", opts: DefaultCompileOptions, s: "n" + strings.Repeat(" ", 5000), limit: "length"},
+		{name: "tokens", opts: CompileOptions{MaxTokens: 10}, s: "n + n + n + n + n + n", limit: "tokens"},
+		{name: "nodes", opts: CompileOptions{MaxNodes: 20}, s: "n + n + n + n + n + n", limit: "nodes"},
+		{name: "ternary", opts: DefaultCompileOptions, s: "n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n % 100 >= 3 && n % 100 <= 10 ? 3 : n % 100 >= 11 ? 4 : 5"},
+	} {
+		_, err := tt.opts.Compile(tt.s)
+		var le *LimitError
+		if tt.limit == "" {
+			if err != nil {
+				t.Errorf("%s: %+v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.As(err, &le) || le.Limit != tt.limit {
+			t.Errorf("%s: err=%v, want %s limit", tt.name, err, tt.limit)
+		}
+	}
+}
+
+func TestCompileMode(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		strict   bool // strict 模式是否通过
+		warnings []string
+	}{
+		{s: "n != 1", strict: true},
+		{s: "n != 1;", strict: true},
+		{s: "n != 1; n", strict: false},
+		{s: "n != 1;;", strict: false},
+		{s: "n % 10 == 1\n ? 0 : 1", warnings: []string{"at column [11:12]: newline"}},
+		{s: "n % 10 == 1\r\n ? 0 : 1", warnings: []string{"at column [11:13]: newline"}},
+		{s: "n % 10 == 1 \\\n? 0 : 1", warnings: []string{"at column [12:14]: backslash-newline"}},
+		{s: "N != 1", warnings: []string{"at column [0:1]: uppercase variable N"}},
+		{s: "n != 1UL", warnings: []string{"at column [6:8]: integer suffix"}},
+		{s: "n > 1u ? 1 : 0", warnings: []string{"at column [5:6]: integer suffix"}},
+	} {
+		_, err := Compile(tt.s)
+		if tt.strict != (err == nil) {
+			t.Errorf("strict %q: err=%v", tt.s, err)
+		}
+		var warnings []string
+		exp, err := Compile(tt.s, Lenient(), WithWarning(func(w Warning) {
+			warnings = append(warnings, w.String())
+		}))
+		if tt.strict || tt.warnings != nil {
+			if err != nil {
+				t.Errorf("lenient %q: err=%v", tt.s, err)
+				continue
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("lenient %q: warnings=%q, want %q", tt.s, warnings, tt.warnings)
+			}
+			if got, _ := exp.Eval(1); got != 0 {
+				t.Errorf("lenient %q: Eval(1)=%d", tt.s, got)
+			}
+		}
+	}
+}
+
+func TestIntegerSuffix(t *testing.T) {
+	for _, tt := range []struct {
+		s  string
+		ok bool
+	}{
+		{s: "1u", ok: true},
+		{s: "1LL", ok: true},
+		{s: "1uLL", ok: true},
+		{s: "1llU", ok: true},
+		{s: "1Ul", ok: true},
+		{s: "1uuu"},
+		{s: "1ulu"},
+		{s: "1lL"},
+		{s: "1lll"},
+		{s: "1uLLu"},
+	} {
+		_, err := Compile("n != "+tt.s, Lenient())
+		if tt.ok != (err == nil) {
+			t.Errorf("%q: err=%v, want ok=%v", tt.s, err, tt.ok)
+		}
+	}
+}
@@ -2,46 +2,74 @@ package plurals
 
 import "fmt"
 
-func Compile(s string) (Expression, error) {
-	tokens, err := Lex(s)
-	if err != nil {
-		return nil, err
+func Compile(s string, opts ...Option) (Expression, error) {
+	o := DefaultCompileOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
-	return parse(tokens)
+	return o.Compile(s)
+}
+
+type parser struct {
+	tokens []Token
+	total  int
+	opts   CompileOptions
+	depth  int // 当前嵌套深度
+	nodes  int // 已创建节点数
 }
 
-func parse(tokens []Token) (node Expression, err error) {
+func (o CompileOptions) parse(tokens []Token) (node Expression, err error) {
 	index := 0
 	total := len(tokens)
 	if total == 0 {
 		err = fmt.Errorf("empty token")
 		return
 	}
-	index, node, err = parseExpression(tokens, total, index)
+	p := &parser{tokens: tokens, total: total, opts: o}
+	index, node, err = p.parseExpression(index)
 	if err != nil {
 		return
 	}
-	for _, ok := get(tokens, total, index); ok; {
-		_, index, err = consume(tokens, total, index, TokenTypeCOM, ";")
-		if err != nil {
-			return
-		}
+	// plural : expression ';'	分号可省略, 但其后不能再有内容
+	if token, ok := p.get(index); ok && token.Type == TokenTypeCOM {
+		index++
+	}
+	if token, ok := p.get(index); ok {
+		err = fmt.Errorf("unexpected token %v after expression", token)
+		return
 	}
 	return node, err
 }
 
-func consume(tokens []Token, total, idx int, expType TokenType, expVal string) (
+// enter 在递归进入一层时调用, 返回的函数用于退出
+func (p *parser) enter() (leave func(), err error) {
+	p.depth++
+	if p.opts.MaxDepth > 0 && p.depth > p.opts.MaxDepth {
+		return nil, &LimitError{Limit: "depth", Max: p.opts.MaxDepth}
+	}
+	return func() { p.depth-- }, nil
+}
+
+func (p *parser) newNode() error {
+	p.nodes++
+	if p.opts.MaxNodes > 0 && p.nodes > p.opts.MaxNodes {
+		return &LimitError{Limit: "nodes", Max: p.opts.MaxNodes}
+	}
+	return nil
+}
+
+func (p *parser) consume(idx int, expType TokenType, expVal string) (
 	token Token,
 	index int,
 	err error,
 ) {
 	index = idx
-	if index >= total {
+	if index >= p.total {
 		err = fmt.Errorf("expected `%v(%v)` after token %v",
-			expVal, expType, tokens[total-1])
+			expVal, expType, p.tokens[p.total-1])
 		return
 	}
-	token = tokens[index]
+	token = p.tokens[index]
 	if token.Type != expType {
 		err = fmt.Errorf("expected `%v(%v)`, but got %v",
 			expVal, expType, token)
@@ -56,258 +84,199 @@ func consume(tokens []Token, total, idx int, expType TokenType, expVal string) (
 	return
 }
 
-func get(tokens []Token, total, index int) (Token, bool) {
-	if index < total {
-		return tokens[index], true
+func (p *parser) get(index int) (Token, bool) {
+	if index < p.total {
+		return p.tokens[index], true
 	}
 	return Token{}, false
 }
 
-func parseExpression(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	return parseTernary(tokens, total, idx)
+func (p *parser) parseExpression(idx int) (index int, node Expression, err error) {
+	leave, err := p.enter()
+	if err != nil {
+		return idx, nil, err
+	}
+	defer leave()
+	return p.parseLevel(idx, 0)
 }
 
-func parseTernary(tokens []Token, total, idx int) (index int, node Expression, err error) {
+// parseLevel 解析优先级不低于 levels[prec] 的表达式
+func (p *parser) parseLevel(idx, prec int) (index int, node Expression, err error) {
+	if prec == len(levels) {
+		return p.parsePrimary(idx)
+	}
+	lv := levels[prec]
+	switch lv.arity {
+	case 1:
+		return p.parsePrefix(idx, prec)
+	case 3:
+		return p.parseTernary(idx, prec)
+	}
 	index = idx
-	// logicOr ( '?' exp ':' exp )?
-	index, node, err = parseLogicOr(tokens, total, index)
+	// operand ( op operand )*
+	var operands []Expression
+	var ops []string
+	index, node, err = p.parseLevel(index, prec+1)
 	if err != nil {
 		return
 	}
-	if token, ok := get(tokens, total, index); ok && token.Type == TokenTypeQST {
-		_, index, err = consume(tokens, total, index, TokenTypeQST, "?")
-		if err != nil {
-			return
-		}
-		var branchTrue Expression
-		index, branchTrue, err = parseExpression(tokens, total, index)
-		if err != nil {
-			return
-		}
-		_, index, err = consume(tokens, total, index, TokenTypeCOL, ":")
-		if err != nil {
-			return
-		}
-		var branchFalse Expression
-		index, branchFalse, err = parseExpression(tokens, total, index)
-		if err != nil {
-			return
-		}
-		node = &TernaryNode{
-			Condition:   node,
-			BranchTrue:  branchTrue,
-			BranchFalse: branchFalse,
-		}
-		return
-	}
-	return
-}
-
-func parseLogicOr(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	index = idx
-	var exp []Expression
-	for token, ok := get(tokens, total, index); ok && (
-	// logicAnd ( || logicAnd )*	index==idx 时是第一个 logicAnd
-	index == idx || token.Value == "||"); token, ok = get(tokens, total, index) {
-		if token.Value == "||" {
-			_, index, err = consume(tokens, total, index, TokenTypeLGC, "||")
+	operands = append(operands, node)
+	for token, ok := p.get(index); ok && lv.match(token) != nil; token, ok = p.get(index) {
+		op := lv.match(token)
+		index++
+		if op.parse != nil {
+			// 右侧不是普通表达式, 如 in 2..4
+			if err = p.newNode(); err != nil {
+				return
+			}
+			index, operands[0], err = op.parse(p, index, operands[0], token.Value)
 			if err != nil {
 				return
 			}
+			continue
 		}
-		index, node, err = parseLogicAnd(tokens, total, index)
+		index, node, err = p.parseLevel(index, prec+1)
 		if err != nil {
 			return
 		}
-		exp = append(exp, node)
-	}
-	node = &LogicNode{Op: "||", Exps: exp}
-	return
-}
-
-func parseLogicAnd(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	index = idx
-	var exp []Expression
-	for token, ok := get(tokens, total, index); ok && (
-	// equality ( && equality )*	index==idx 时是第一个 equality
-	index == idx || token.Value == "&&"); token, ok = get(tokens, total, index) {
-		if token.Value == "&&" {
-			_, index, err = consume(tokens, total, index, TokenTypeLGC, "&&")
-			if err != nil {
+		if lv.assoc == assocLeft {
+			// (a op b) op c
+			if node, err = p.build(op.build, []Expression{operands[0], node}, []string{token.Value}); err != nil {
 				return
 			}
+			operands[0] = node
+			continue
 		}
-		index, node, err = parseEquality(tokens, total, index)
-		if err != nil {
-			return
-		}
-		exp = append(exp, node)
+		ops = append(ops, token.Value)
+		operands = append(operands, node)
 	}
-	node = &LogicNode{Op: "&&", Exps: exp}
+	if lv.assoc == assocLeft {
+		return index, operands[0], nil
+	}
+	node, err = p.build(lv.build, operands, ops)
 	return
 }
 
-func parseEquality(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	index = idx
-	index, node, err = parseRelational(tokens, total, index)
+// parseTernary: operand ( '?' expression ':' expression )?
+func (p *parser) parseTernary(idx, prec int) (index int, node Expression, err error) {
+	lv := levels[prec]
+	index, node, err = p.parseLevel(idx, prec+1)
 	if err != nil {
 		return
 	}
-	if token, ok := get(tokens, total, index); ok && token.Type == TokenTypeEQU {
-		_, index, err = consume(tokens, total, index, TokenTypeEQU, "")
-		if err != nil {
-			return
-		}
-		ret := &CompareNode{
-			Exp: node,
-			Op:  token.Value,
-		}
-		node = ret
-		index, ret.Other, err = parseRelational(tokens, total, index)
-		if err != nil {
-			return
-		}
+	token, ok := p.get(index)
+	if !ok || lv.match(token) == nil {
+		return
 	}
-	return
-}
-
-func parseRelational(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	index = idx
-	index, node, err = parseAdd(tokens, total, index)
+	index++
+	var branchTrue, branchFalse Expression
+	index, branchTrue, err = p.parseExpression(index)
 	if err != nil {
 		return
 	}
-	if token, ok := get(tokens, total, index); ok && token.Type == TokenTypeCMP {
-		_, index, err = consume(tokens, total, index, TokenTypeCMP, "")
-		if err != nil {
-			return
-		}
-		ret := &CompareNode{
-			Exp: node,
-			Op:  token.Value,
-		}
-		node = ret
-		index, ret.Other, err = parseAdd(tokens, total, index)
-		if err != nil {
-			return
-		}
-	}
-	return
-}
-
-func parseAdd(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	index = idx
-	var exp Expression
-	index, exp, err = parseMul(tokens, total, index)
+	_, index, err = p.consume(index, TokenTypeCOL, lv.ops[0].close)
 	if err != nil {
 		return
 	}
-	var op []string
-	var other []Expression
-	for token, ok := get(tokens, total, index); ok && token.Type == TokenTypeADD; token, ok = get(tokens, total, index) {
-		_, index, err = consume(tokens, total, index, TokenTypeADD, "")
-		if err != nil {
-			return
-		}
-		index, node, err = parseMul(tokens, total, index)
-		if err != nil {
-			return
-		}
-		op = append(op, token.Value)
-		other = append(other, node)
-	}
-	node = &BinaryNExp{
-		Exp:   exp,
-		Op:    op,
-		Other: other,
+	index, branchFalse, err = p.parseExpression(index)
+	if err != nil {
+		return
 	}
+	node, err = p.build(lv.build, []Expression{node, branchTrue, branchFalse}, []string{token.Value})
 	return
 }
 
-func parseMul(tokens []Token, total, idx int) (index int, node Expression, err error) {
+// parsePrefix: op* operand
+func (p *parser) parsePrefix(idx, prec int) (index int, node Expression, err error) {
+	lv := levels[prec]
 	index = idx
-	var exp Expression
-	index, exp, err = parseUnary(tokens, total, index)
+	var ops []Token
+	for token, ok := p.get(index); ok && lv.match(token) != nil; token, ok = p.get(index) {
+		index++
+		ops = append(ops, token)
+	}
+	if p.opts.MaxDepth > 0 && p.depth+len(ops) > p.opts.MaxDepth {
+		err = &LimitError{Limit: "depth", Max: p.opts.MaxDepth}
+		return
+	}
+	index, node, err = p.parseLevel(index, prec+1)
 	if err != nil {
 		return
 	}
-	var op []string
-	var other []Expression
-	for token, ok := get(tokens, total, index); ok && token.Type == TokenTypeMUL; token, ok = get(tokens, total, index) {
-		_, index, err = consume(tokens, total, index, TokenTypeMUL, "")
-		if err != nil {
-			return
-		}
-		index, node, err = parseUnary(tokens, total, index)
-		if err != nil {
-			return
-		}
-		op = append(op, token.Value)
-		other = append(other, node)
+	if len(ops) == 0 {
+		node, err = p.build(lv.build, []Expression{node}, nil)
+		return
 	}
-	node = &BinaryNExp{
-		Exp:   exp,
-		Op:    op,
-		Other: other,
+	for i := len(ops) - 1; i >= 0 && err == nil; i-- {
+		end := SpanOf(node).End
+		if node, err = p.build(lv.build, []Expression{node}, []string{ops[i].Value}); err == nil {
+			setSpan(node, Span{Start: ops[i].Start, End: end})
+		}
 	}
 	return
 }
 
-func parseUnary(tokens []Token, total, idx int) (index int, node Expression, err error) {
-	index = idx
-	op := ""
-	if token, ok := get(tokens, total, index); ok && token.Value == "!" {
-		_, index, err = consume(tokens, total, index, TokenTypeLGC, "!")
-		if err != nil {
-			return
-		}
-		op = "!"
-	}
-	index, node, err = parsePrimary(tokens, total, index)
-	if err != nil {
-		return
+func (p *parser) build(build buildFunc, operands []Expression, ops []string) (Expression, error) {
+	if err := p.newNode(); err != nil {
+		return nil, err
 	}
-	node = &UnaryExp{Op: op, Exp: node}
-	return
+	node := build(operands, ops)
+	// 从第一个操作数开始, 到最后一个操作数结束
+	setSpan(node, Span{Start: SpanOf(operands[0]).Start, End: SpanOf(operands[len(operands)-1]).End})
+	return node, nil
 }
 
-func parsePrimary(tokens []Token, total, idx int) (index int, node Expression, err error) {
+func (p *parser) parsePrimary(idx int) (index int, node Expression, err error) {
 	index = idx
-	if token, ok := get(tokens, total, index); ok {
+	if token, ok := p.get(index); ok {
 		switch token.Type {
 		case TokenTypeIDN:
-			_, index, err = consume(tokens, total, index, TokenTypeIDN, "n")
+			_, index, err = p.consume(index, TokenTypeIDN, "")
 			if err != nil {
 				return
 			}
-			node = &PrimaryNode{Type: token.Type}
+			if err = p.newNode(); err != nil {
+				return
+			}
+			primary := &PrimaryNode{Type: token.Type, Span: Span{Start: token.Start, End: token.End}}
+			if token.Value != "n" && token.Value != "N" {
+				primary.Name = token.Value
+			}
+			node = primary
 			return
 		case TokenTypeNUM:
-			_, index, err = consume(tokens, total, index, TokenTypeNUM, "")
+			_, index, err = p.consume(index, TokenTypeNUM, "")
 			if err != nil {
 				return
 			}
-			node = &PrimaryNode{Type: token.Type, Num: token.Number}
+			if err = p.newNode(); err != nil {
+				return
+			}
+			node = &PrimaryNode{Type: token.Type, Num: token.Number, Big: bigLiteral(token.Value), Span: Span{Start: token.Start, End: token.End}}
 			return
 		case TokenTypeLPA:
-			_, index, err = consume(tokens, total, index, TokenTypeLPA, "(")
+			_, index, err = p.consume(index, TokenTypeLPA, "(")
 			if err != nil {
 				return
 			}
-			index, node, err = parseExpression(tokens, total, index)
+			index, node, err = p.parseExpression(index)
 			if err != nil {
 				return
 			}
-			_, index, err = consume(tokens, total, index, TokenTypeRPA, ")")
+			var rpa Token
+			rpa, index, err = p.consume(index, TokenTypeRPA, ")")
 			if err != nil {
 				return
 			}
-			node = &PrimaryNode{Type: token.Type, Exp: node}
+			if err = p.newNode(); err != nil {
+				return
+			}
+			node = &PrimaryNode{Type: token.Type, Exp: node, Span: Span{Start: token.Start, End: rpa.End}}
 			return
 		}
 		err = fmt.Errorf("expected ID, NUM, or '(', but got token %v", token)
 		return
 	}
-	err = fmt.Errorf("expected ID, NUM, or '(' after token %v", tokens[total-1])
+	err = fmt.Errorf("expected ID, NUM, or '(' after token %v", p.tokens[p.total-1])
 	return
 }
@@ -2,6 +2,7 @@ package plurals
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -25,6 +26,7 @@ func TestCompile(t *testing.T) {
 		{exp: "n==;n", err: true},
 		{exp: "1>!", err: true},
 		{exp: "! == 1", err: true},
+		{exp: "n ? 1 : ", err: true},
 	} {
 		exp, err := Compile(tt.exp)
 		t.Logf("%q: exp=%v, err=%+v", tt.exp, exp, err)
@@ -36,6 +38,12 @@ func TestCompile(t *testing.T) {
 			t.Errorf("got: %v, want: %s", exp, tt.exp)
 			continue
 		}
+		if err == nil {
+			// 去掉空格后语法树相同
+			if other, err := Compile(normalize(tt.exp)); err != nil || !Equal(exp, other) || Hash(exp) != Hash(other) {
+				t.Errorf("compile %q: got %v, err=%+v, want the same tree", normalize(tt.exp), other, err)
+			}
+		}
 		if err == nil {
 			for n := range int64(3) {
 				got, err := Eval(tt.exp, n)
@@ -63,3 +71,155 @@ func TestEval(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalShortCircuit(t *testing.T) {
+	for _, tt := range []struct {
+		exp  string
+		want int64
+	}{
+		{exp: "n == 0 || 10 / n > 1", want: 1},
+		{exp: "n != 0 && 10 % n", want: 0},
+	} {
+		got, err := Eval(tt.exp, 0)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got=%v, err=%v, want=%v", tt.exp, got, err, tt.want)
+		}
+	}
+}
+
+func TestEvalAllocs(t *testing.T) {
+	for _, s := range []string{
+		"n != 1",
+		"n%10==1&&n%100!=11?0:n!=0?1:2",
+		"n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && n % 10 <= 4 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2",
+		"(n % 10 == 1) ? 0 : (n == 0) ? 1 : 2",
+	} {
+		Eval(s, 1)
+		if allocs := testing.AllocsPerRun(100, func() { Eval(s, 21) }); allocs != 0 {
+			t.Errorf("Eval(%q) allocs=%v, want 0", s, allocs)
+		}
+		r, err := NewRule(s)
+		if err != nil {
+			t.Fatalf("NewRule(%q): %+v", s, err)
+		}
+		if allocs := testing.AllocsPerRun(100, func() { r.Eval(21) }); allocs != 0 {
+			t.Errorf("Rule(%q).Eval allocs=%v, want 0", s, allocs)
+		}
+	}
+}
+
+func TestRule(t *testing.T) {
+	for s, f := range commons {
+		r, err := NewRule(strings.ReplaceAll(s, "==", " == "))
+		if err != nil {
+			t.Fatalf("NewRule(%q): %+v", s, err)
+		}
+		if r.fn == nil || r.Expression() == nil {
+			t.Errorf("NewRule(%q) should use the common rule", s)
+		}
+		for n := range int64(200) {
+			got, _ := r.Eval(n)
+			want, _ := r.Expression().Eval(n)
+			if got != want || got != f(n) {
+				t.Errorf("%q n=%d: got=%v, exp=%v, want=%v", s, n, got, want, f(n))
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	s := "n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2"
+	b.ReportAllocs()
+	for i := range b.N {
+		Eval(s, int64(i))
+	}
+}
+
+// cConformance 的结果由 C 编译器计算, n 为 long 类型, 依次为 conformanceN 中的值
+var conformanceN = []int64{0, 1, 2, 3, 5, 11, 12, 100}
+
+var cConformance = []struct {
+	exp  string
+	want []int64
+}{
+	{exp: "n == 1 == 1", want: []int64{0, 1, 0, 0, 0, 0, 0, 0}},
+	{exp: "n == 1 == 0", want: []int64{1, 0, 1, 1, 1, 1, 1, 1}},
+	{exp: "1 < n < 5", want: []int64{1, 1, 1, 1, 1, 1, 1, 1}},
+	{exp: "5 > n > 1", want: []int64{0, 0, 0, 0, 0, 0, 0, 0}},
+	{exp: "n < 5 == 1", want: []int64{1, 1, 1, 1, 0, 0, 0, 0}},
+	{exp: "n != 2 != 1", want: []int64{0, 0, 1, 0, 0, 0, 0, 0}},
+	{exp: "n == 1 != n == 2", want: []int64{0, 0, 0, 0, 0, 0, 0, 0}},
+	{exp: "n < 3 >= n > 0", want: []int64{1, 1, 0, 0, 0, 0, 0, 0}},
+	{exp: "n % 10 < 5 < 1", want: []int64{0, 0, 0, 0, 1, 0, 0, 0}},
+	{exp: "!n", want: []int64{1, 0, 0, 0, 0, 0, 0, 0}},
+	{exp: "!!n", want: []int64{0, 1, 1, 1, 1, 1, 1, 1}},
+	{exp: "!!!n", want: []int64{1, 0, 0, 0, 0, 0, 0, 0}},
+	{exp: "!(!n)", want: []int64{0, 1, 1, 1, 1, 1, 1, 1}},
+	{exp: "!!(n % 10)", want: []int64{0, 1, 1, 1, 1, 1, 1, 0}},
+	{exp: "!n == 0", want: []int64{0, 1, 1, 1, 1, 1, 1, 1}},
+	{exp: "!n + 1", want: []int64{2, 1, 1, 1, 1, 1, 1, 1}},
+	{exp: "!!n * 3 + 1", want: []int64{1, 4, 4, 4, 4, 4, 4, 4}},
+	{exp: "n >= 2 == n <= 4", want: []int64{0, 0, 1, 1, 0, 0, 0, 0}},
+	{exp: "n == 0 ? 0 : n == 1 ? 1 : 2", want: []int64{0, 1, 2, 2, 2, 2, 2, 2}},
+	{exp: "n > 1 ? n > 5 ? 2 : 1 : 0", want: []int64{0, 0, 1, 1, 1, 2, 2, 2}},
+	{exp: "n || 0 && 0", want: []int64{0, 1, 1, 1, 1, 1, 1, 1}},
+	{exp: "n && 1 || 0 ? 3 : 4", want: []int64{4, 3, 3, 3, 3, 3, 3, 3}},
+	{exp: "n - 1 - 1", want: []int64{-2, -1, 0, 1, 3, 9, 10, 98}},
+	{exp: "n / 2 / 2", want: []int64{0, 0, 0, 0, 1, 2, 3, 25}},
+	{exp: "n % 7 % 3", want: []int64{0, 1, 2, 0, 2, 1, 2, 2}},
+	{exp: "n - 2 * 3 + 4", want: []int64{-2, -1, 0, 1, 3, 9, 10, 98}},
+}
+
+func TestConformance(t *testing.T) {
+	for _, tt := range cConformance {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Errorf("compile %q: %+v", tt.exp, err)
+			continue
+		}
+		again, err := Compile(fmt.Sprintf("%v", exp))
+		if err != nil {
+			t.Errorf("compile %q printed as %v: %+v", tt.exp, exp, err)
+			continue
+		}
+		for i, n := range conformanceN {
+			got, err := exp.Eval(n)
+			if err != nil || got != tt.want[i] {
+				t.Errorf("%q n=%d: got=%d, err=%v, want=%d", tt.exp, n, got, err, tt.want[i])
+			}
+			if got, _ := again.Eval(n); got != tt.want[i] {
+				t.Errorf("%v n=%d: got=%d, want=%d", again, n, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestCacheBound(t *testing.T) {
+	for i := range maxCached + 10 {
+		if _, err := Eval(fmt.Sprintf("n == %d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(rules) > maxCached || len(cache) > maxCached || len(canons) > maxCached {
+		t.Errorf("caches grow beyond %d: %d %d %d", maxCached, len(rules), len(cache), len(canons))
+	}
+}
+
+func TestBuildLevelsPanics(t *testing.T) {
+	for _, ops := range [][]operator{
+		{{value: "^", prec: 0, assoc: assocRight, arity: 2, build: buildBinary}},
+		{{value: "+", prec: 0, assoc: assocList, arity: 2, build: buildBinary}, {value: "!", prec: 0, assoc: assocRight, arity: 1, build: buildUnary}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("buildLevels(%q...) should panic", ops[0].value)
+				}
+			}()
+			buildLevels(ops)
+		}()
+	}
+}
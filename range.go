@@ -0,0 +1,107 @@
+package plurals
+
+import "fmt"
+
+// Run is a span of consecutive n, From to To inclusive, that select the same Form.
+type Run struct {
+	Form     int64
+	From, To int64
+}
+
+// EvalBatch evaluates exp for every n in ns, storing the results in out.
+func EvalBatch(exp Expression, ns []int64, out []int64) error {
+	if len(out) < len(ns) {
+		return fmt.Errorf("out is too short: len(out)=%d, len(ns)=%d", len(out), len(ns))
+	}
+	for i, n := range ns {
+		val, err := exp.Eval(n)
+		if err != nil {
+			return fmt.Errorf("n=%d: %w", n, err)
+		}
+		out[i] = val
+	}
+	return nil
+}
+
+// EvalRange evaluates exp for every n in [lo, hi], as run-length encoded results.
+// When exp only uses n in `n % c` or compares n with constants, exp is periodic
+// outside its thresholds: one period is evaluated and the rest is repeated.
+func EvalRange(exp Expression, lo, hi int64) (runs []Run, err error) {
+	err = scanRuns(exp, lo, hi, func(r Run) bool {
+		if last := len(runs) - 1; last >= 0 && runs[last].Form == r.Form && runs[last].To+1 == r.From {
+			runs[last].To = r.To
+			return true
+		}
+		runs = append(runs, r)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// scanRuns yields the results of exp over [lo, hi] in order, until yield returns false.
+// Consecutive runs may have the same form.
+func scanRuns(exp Expression, lo, hi int64, yield func(Run) bool) error {
+	if lo > hi {
+		return nil
+	}
+	s, ok := analyze(exp)
+	if !ok {
+		for n := lo; ; n++ {
+			val, err := exp.Eval(n)
+			if err != nil {
+				return fmt.Errorf("n=%d: %w", n, err)
+			}
+			if !yield(Run{Form: val, From: n, To: n}) || n == hi {
+				return nil
+			}
+		}
+	}
+	for from, to := range s.pieces(lo) {
+		to = min(to, hi)
+		if cont, err := periodic(exp, from, to, s.period, yield); err != nil || !cont || to == hi {
+			return err
+		}
+	}
+	return nil
+}
+
+// periodic yields the results over [from, to] where f(m) == f(m+period):
+// the first period is evaluated and then repeated.
+func periodic(exp Expression, from, to, period int64, yield func(Run) bool) (bool, error) {
+	span := uint64(to) - uint64(from)
+	var pattern []Run // From, To 是相对 from 的偏移
+	for off := uint64(0); off < uint64(period) && off <= span; off++ {
+		n := from + int64(off)
+		val, err := exp.Eval(n)
+		if err != nil {
+			return false, fmt.Errorf("n=%d: %w", n, err)
+		}
+		if last := len(pattern) - 1; last >= 0 && pattern[last].Form == val {
+			pattern[last].To++
+			continue
+		}
+		pattern = append(pattern, Run{Form: val, From: int64(off), To: int64(off)})
+	}
+	if len(pattern) == 1 {
+		return yield(Run{Form: pattern[0].Form, From: from, To: to}), nil
+	}
+	for base := uint64(0); base <= span; base += uint64(period) {
+		for _, r := range pattern {
+			start := base + uint64(r.From)
+			if start > span {
+				return true, nil
+			}
+			end := min(base+uint64(r.To), span)
+			if !yield(Run{Form: r.Form, From: from + int64(start), To: from + int64(end)}) {
+				return false, nil
+			}
+		}
+		if base+uint64(period) < base {
+			break
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,104 @@
+package plurals
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEvalBatch(t *testing.T) {
+	exp, _ := Compile("n % 10 == 1 && n % 100 != 11 ? 0 : n != 0 ? 1 : 2")
+	ns := []int64{0, 1, 2, 11, 21, 111, -1}
+	out := make([]int64, len(ns))
+	if err := EvalBatch(exp, ns, out); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{2, 0, 1, 1, 0, 1, 1}; !reflect.DeepEqual(out, want) {
+		t.Errorf("got=%v, want=%v", out, want)
+	}
+	if err := EvalBatch(exp, ns, out[:1]); err == nil {
+		t.Errorf("want error for short out")
+	}
+	div, _ := Compile("10 / n")
+	if err := EvalBatch(div, ns, out); err == nil {
+		t.Errorf("want divide zero error")
+	}
+}
+
+func TestEvalRange(t *testing.T) {
+	exps := []string{
+		"n * 2 % 7 == 3",
+		"n / 10 % 3",
+		"n >= 1000 ? n % 7 : 2",
+		"( 5 + 5 ) > n % 13 ? 1 : 0",
+		"n < 0 - 20 ? n % 3 : n > 0 - 5 && n < 30 ? 2 : n % 4",
+		// 阈值之间跨过 0 的 n % c
+		"n > 0 - 100 && n < 100 ? n % 10 == 9 : 2",
+	}
+	for s := range commons {
+		exps = append(exps, s)
+	}
+	for _, s := range exps {
+		exp, err := Compile(s)
+		if err != nil {
+			t.Fatalf("compile %q: %+v", s, err)
+		}
+		for _, r := range [][2]int64{
+			{0, 1000}, {-1500, 1500}, {-150, 150}, {-7, -7}, {990, 1210},
+			{math.MaxInt64 - 300, math.MaxInt64}, {math.MinInt64, math.MinInt64 + 300},
+		} {
+			runs, err := EvalRange(exp, r[0], r[1])
+			if err != nil {
+				t.Fatalf("%q %v: %+v", s, r, err)
+			}
+			n := r[0]
+			for i, run := range runs {
+				if run.From != n || run.To < run.From || (i > 0 && runs[i-1].Form == run.Form) {
+					t.Fatalf("%q %v: bad run %d %+v", s, r, i, run)
+				}
+				for m := run.From; ; m++ {
+					if want, _ := exp.Eval(m); want != run.Form {
+						t.Fatalf("%q n=%d: got=%d, want=%d", s, m, run.Form, want)
+					}
+					if m == run.To {
+						break
+					}
+				}
+				n = run.To + 1
+			}
+			if runs[len(runs)-1].To != r[1] {
+				t.Fatalf("%q %v: runs end at %d", s, r, runs[len(runs)-1].To)
+			}
+		}
+	}
+}
+
+func TestEvalRangeHuge(t *testing.T) {
+	exp, _ := Compile("n != 1")
+	runs, err := EvalRange(exp, math.MinInt64, math.MaxInt64)
+	want := []Run{
+		{Form: 1, From: math.MinInt64, To: 0},
+		{Form: 0, From: 1, To: 1},
+		{Form: 1, From: 2, To: math.MaxInt64},
+	}
+	if err != nil || !reflect.DeepEqual(runs, want) {
+		t.Errorf("got=%v, err=%v, want=%v", runs, err, want)
+	}
+}
+
+func TestEvalRangeThresholds(t *testing.T) {
+	exp, _ := Compile("n > 100000000 ? 1 : n == 50000001 ? 2 : 0")
+	runs, err := EvalRange(exp, 0, 1<<62)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Run{
+		{Form: 0, From: 0, To: 50000000},
+		{Form: 2, From: 50000001, To: 50000001},
+		{Form: 0, From: 50000002, To: 100000000},
+		{Form: 1, From: 100000001, To: 1 << 62},
+	}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("got=%v, want=%v", runs, want)
+	}
+}
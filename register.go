@@ -0,0 +1,93 @@
+package plurals
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+)
+
+// MismatchError is returned by Register when fn disagrees with the expression.
+type MismatchError struct {
+	Expr string
+	N    int64
+	Got  int64 // fn(N)
+	Want int64 // 表达式的结果
+	Err  error // 表达式求值出错时不为空
+}
+
+func (e *MismatchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("rule %q fails at n=%d: %v", e.Expr, e.N, e.Err)
+	}
+	return fmt.Sprintf("rule %q: fn(%d)=%d, want %d", e.Expr, e.N, e.Got, e.Want)
+}
+
+// Register makes Eval and NewRule use fn for expr and any expression
+// equivalent to it, also when expr is a well-known rule; registering expr
+// again replaces fn. fn is checked against
+// the compiled expr before it is accepted: over every residue and threshold
+// when expr is periodic, and over a large sample of n in any case.
+// Identify only knows the well-known rules, it does not use fn.
+func Register(expr string, fn func(n int64) int64) error {
+	expr = normalize(expr)
+	exp, err := Compile(expr)
+	if err != nil {
+		return err
+	}
+	check := func(n int64) error {
+		want, err := exp.Eval(n)
+		if got := fn(n); err != nil || got != want {
+			return &MismatchError{Expr: expr, N: n, Got: got, Want: want, Err: err}
+		}
+		return nil
+	}
+	if s, ok := analyze(exp); ok && s.comparable() {
+		for n := s.lo - s.period; n <= s.hi+s.period; n++ {
+			if err := check(n); err != nil {
+				return err
+			}
+		}
+	}
+	for _, n := range checkSamples() {
+		if err := check(n); err != nil {
+			return err
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if i := slices.IndexFunc(registered, func(r knownRule) bool { return Equal(r.exp, exp) }); i >= 0 {
+		registered[i].fn = fn
+	} else {
+		registered = append(registered, knownRule{exp: exp, fn: fn})
+	}
+	custom[expr] = fn
+	clear(rules) // 已创建的 Rule 重新查找
+	clear(canons)
+	return nil
+}
+
+var (
+	registered []knownRule                      // 由 mu 保护
+	custom     = map[string]func(int64) int64{} // 由 mu 保护
+)
+
+// checkSamples 返回 Register 检查的 n
+var checkSamples = sync.OnceValue(func() []int64 {
+	var ns []int64
+	for n := int64(-1000); n <= 100000; n++ {
+		ns = append(ns, n)
+	}
+	for p := int64(10); p < math.MaxInt64/100; p *= 10 {
+		for d := int64(-25); d <= 25; d++ {
+			ns = append(ns, p*100+d, -p*100+d)
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for range 10000 {
+		ns = append(ns, r.Int63(), -r.Int63())
+	}
+	ns = append(ns, math.MaxInt64, math.MinInt64)
+	return ns
+})
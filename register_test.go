@@ -0,0 +1,128 @@
+package plurals
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+)
+
+// resetRegistered 在测试结束后恢复 Register 修改的全局状态
+func resetRegistered(t *testing.T) {
+	mu.Lock()
+	saved, savedCustom := slices.Clone(registered), maps.Clone(custom)
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		registered, custom = saved, savedCustom
+		clear(rules)
+		clear(canons)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistered(t)
+	// Icelandic
+	calls := 0
+	icelandic := func(n int64) int64 {
+		calls++
+		return i(n%10 != 1 || n%100 == 11)
+	}
+	if err := Register("n % 10 != 1 || n % 100 == 11", icelandic); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"n%10!=1||n%100==11", "(n % 10 != 1 || n % 100 == 11)", "n % 10 == 1 && n % 100 != 11 ? 0 : 1"} {
+		calls = 0
+		for n := range int64(200) {
+			got, err := Eval(s, n)
+			if err != nil || got != i(n%10 != 1 || n%100 == 11) {
+				t.Errorf("%q n=%d: got=%d, err=%v", s, n, got, err)
+			}
+		}
+		if calls != 200 {
+			t.Errorf("%q: registered fn called %d times", s, calls)
+		}
+	}
+
+	var mismatch *MismatchError
+	err := Register("n % 100 == 1 ? 0 : 1", func(n int64) int64 { return i(n%10 != 1) })
+	if !errors.As(err, &mismatch) || mismatch.N != 11 || mismatch.Got != 0 || mismatch.Want != 1 {
+		t.Errorf("want mismatch at n=11, got %v", err)
+	}
+	err = Register("n > 1000000 ? 1 : 0", func(n int64) int64 { return i(n > 100000) })
+	if !errors.As(err, &mismatch) || mismatch.N != 100001 {
+		t.Errorf("want mismatch at n=100001, got %v", err)
+	}
+	err = Register("n * 3 % 7", func(n int64) int64 { return n * 3 % 7 })
+	if err != nil {
+		t.Errorf("Register: %v", err)
+	}
+	err = Register("10 / n", func(n int64) int64 {
+		if n == 0 {
+			return 0
+		}
+		return 10 / n
+	})
+	if !errors.As(err, &mismatch) || mismatch.N != 0 || !errors.Is(mismatch.Err, ErrDivideZero) {
+		t.Errorf("want divide zero mismatch, got %v", err)
+	}
+	if err := Register("n +", icelandic); err == nil {
+		t.Errorf("want compile error")
+	}
+}
+
+func TestRegisterOverride(t *testing.T) {
+	resetRegistered(t)
+	// 注册的实现优先于常用规则的原生实现
+	calls := 0
+	err := Register("n!=1", func(n int64) int64 {
+		calls++
+		return i(n != 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"n != 1", "(n != 1)", "n == 1 ? 0 : 1"} {
+		calls = 0
+		if got, err := Eval(s, 5); err != nil || got != 1 || calls != 1 {
+			t.Errorf("Eval(%q, 5)=%d, %v: registered fn called %d times", s, got, err, calls)
+		}
+	}
+}
+
+func TestRegisterReplace(t *testing.T) {
+	resetRegistered(t)
+	before := len(registered)
+	var calls [2]int
+	for k := range calls {
+		err := Register("n == 3 ? 1 : 0", func(n int64) int64 {
+			calls[k]++
+			return i(n == 3)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(registered); got != before+1 {
+		t.Errorf("registered has %d rules, want %d", got, before+1)
+	}
+	calls = [2]int{}
+	if _, err := Eval("n==3 ? 1 : 0", 5); err != nil || calls != [2]int{0, 1} {
+		t.Errorf("err=%v, calls=%v, want the second fn", err, calls)
+	}
+}
+
+func TestCheckSamples(t *testing.T) {
+	samples := checkSamples()
+	p := int64(1e17)
+	if wrapped := p * 100; slices.Contains(samples, wrapped) {
+		t.Errorf("samples contain the overflowed %d", wrapped)
+	}
+	for _, n := range []int64{1e18, 1e18 + 25, -1e18 - 25, math.MaxInt64, math.MinInt64} {
+		if !slices.Contains(samples, n) {
+			t.Errorf("samples do not contain %d", n)
+		}
+	}
+}
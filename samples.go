@@ -0,0 +1,189 @@
+package plurals
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxSamples 每个形式最多列出的范围数
+const maxSamples = 6
+
+// Samples returns a CLDR style sample string of non-negative n for each form
+// of exp, e.g. "@integer 2~4, 22~24, 32~34, …", where the ellipsis means
+// that more n select the form. A form no n selects has an empty string.
+//
+// When exp is periodic (see EvalRange) the ellipsis is exact, otherwise
+// n up to 1000 are sampled and a form seen above 500 is assumed to continue.
+func Samples(exp Expression, nplurals int) ([]string, error) {
+	if nplurals < 0 {
+		return nil, fmt.Errorf("invalid nplurals=%d", nplurals)
+	}
+	end, from := int64(1000), int64(500) // from 之后出现的形式视为会一直出现
+	if s, ok := analyze(exp); ok && s.hi <= maxDescribe-maxSamples*s.period {
+		from = max(s.hi, 0)
+		end = from + maxSamples*s.period
+	}
+	runs, err := EvalRange(exp, 0, end)
+	if err != nil {
+		return nil, err
+	}
+	lists := make([]SampleList, nplurals)
+	for _, r := range runs {
+		if r.Form < 0 || r.Form >= int64(nplurals) {
+			return nil, fmt.Errorf("n=%d selects form %d, want less than nplurals=%d", r.From, r.Form, nplurals)
+		}
+		l := &lists[r.Form]
+		if r.To >= from {
+			l.More = true
+		}
+		if len(l.Ranges) == maxSamples {
+			l.More = true
+			continue
+		}
+		l.Ranges = append(l.Ranges, Range{From: r.From, To: r.To})
+	}
+	out := make([]string, nplurals)
+	for i, l := range lists {
+		out[i] = l.String()
+	}
+	return out, nil
+}
+
+// SampleList is the integer part of a CLDR sample string.
+type SampleList struct {
+	Ranges []Range
+	More   bool // 以省略号结尾
+}
+
+func (l SampleList) String() string {
+	if len(l.Ranges) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("@integer ")
+	for i, r := range l.Ranges {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(strconv.FormatInt(r.From, 10))
+		if r.To != r.From {
+			sb.WriteString("~" + strconv.FormatInt(r.To, 10))
+		}
+	}
+	if l.More {
+		sb.WriteString(", …")
+	}
+	return sb.String()
+}
+
+// ParseSamples parses a CLDR sample string such as
+// "@integer 0, 5~19, 100, 1000, …". The "@integer" keyword is optional,
+// and a following "@decimal" part is ignored. `...` is accepted for `…`,
+// and the compact forms `1c6` and `1e6` for 1000000.
+func ParseSamples(s string) (l SampleList, err error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "@decimal"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(strings.TrimPrefix(s, "@integer"))
+	if s == "" {
+		return
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if l.More {
+			return l, fmt.Errorf("unexpected %q after the ellipsis in samples %q", item, s)
+		}
+		if item == "…" || item == "..." {
+			l.More = true
+			continue
+		}
+		from, to, isRange := strings.Cut(item, "~")
+		var r Range
+		if r.From, err = parseSample(from); err != nil {
+			return l, fmt.Errorf("invalid sample %q: %w", item, err)
+		}
+		r.To = r.From
+		if isRange {
+			if r.To, err = parseSample(to); err != nil {
+				return l, fmt.Errorf("invalid sample %q: %w", item, err)
+			}
+			if r.To < r.From {
+				return l, fmt.Errorf("empty sample range %q", item)
+			}
+		}
+		l.Ranges = append(l.Ranges, r)
+	}
+	return l, nil
+}
+
+// parseSample 解析一个样例数字, 如 5, 1c6 或 1.5e3
+func parseSample(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "ce")
+	if i < 0 {
+		return strconv.ParseInt(s, 10, 64)
+	}
+	exp, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return 0, err
+	}
+	whole, frac, _ := strings.Cut(s[:i], ".")
+	if whole == "" {
+		return 0, fmt.Errorf("missing digits before %q", s[i:])
+	}
+	// 1.5c3 即 15 后补两个 0
+	digits := whole + frac
+	exp -= len(frac)
+	if exp < 0 {
+		cut := len(digits) + exp
+		if cut < 1 || strings.Trim(digits[cut:], "0") != "" {
+			return 0, fmt.Errorf("%q is not an integer", s)
+		}
+		digits, exp = digits[:cut], 0
+	}
+	if exp > 19 {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	return strconv.ParseInt(digits+strings.Repeat("0", exp), 10, 64)
+}
+
+// SampleError is returned by VerifySamples when a sample n does not select
+// the form it is listed for.
+type SampleError struct {
+	N    int64
+	Form int64 // 样例所属的形式
+	Got  int64 // 表达式的结果
+}
+
+func (e *SampleError) Error() string {
+	return fmt.Sprintf("sample n=%d of form %d selects form %d", e.N, e.Form, e.Got)
+}
+
+// VerifySamples checks that every n listed in samples[i] selects form i.
+func VerifySamples(exp Expression, samples []string) error {
+	for i, s := range samples {
+		form := int64(i)
+		l, err := ParseSamples(s)
+		if err != nil {
+			return fmt.Errorf("form %d: %w", form, err)
+		}
+		for _, r := range l.Ranges {
+			var mismatch *SampleError
+			err := scanRuns(exp, r.From, r.To, func(run Run) bool {
+				if run.Form != form {
+					mismatch = &SampleError{N: run.From, Form: form, Got: run.Form}
+				}
+				return mismatch == nil
+			})
+			if err != nil {
+				return fmt.Errorf("form %d: %w", form, err)
+			}
+			if mismatch != nil {
+				return mismatch
+			}
+		}
+	}
+	return nil
+}
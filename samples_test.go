@@ -0,0 +1,103 @@
+package plurals
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSamples(t *testing.T) {
+	for _, tt := range []struct {
+		exp      string
+		nplurals int
+		want     []string
+	}{
+		{exp: "n != 1", nplurals: 2, want: []string{"@integer 1", "@integer 0, 2~8, …"}},
+		{exp: commonRules[7].exp, nplurals: 3, want: []string{ // Russian
+			"@integer 1, 21, 31, 41, 51, 61, …",
+			"@integer 2~4, 22~24, 32~34, 42~44, 52~54, 62~64, …",
+			"@integer 0, 5~20, 25~30, 35~40, 45~50, 55~60, …",
+		}},
+		{exp: "(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2", nplurals: 3, want: []string{
+			"@integer 1", "@integer 2~4", "@integer 0, 5~11, …",
+		}},
+		{exp: "n == 1 ? 0 : n == 2 ? 1 : 3", nplurals: 4, want: []string{"@integer 1", "@integer 2", "", "@integer 0, 3~9, …"}},
+		{exp: "n * n > 20 ? 1 : 0", nplurals: 2, want: []string{"@integer 0~4", "@integer 5~1000, …"}},
+	} {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Samples(exp, tt.nplurals)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Samples(%q)=%q, %v\nwant %q", tt.exp, got, err, tt.want)
+			continue
+		}
+		if err := VerifySamples(exp, got); err != nil {
+			t.Errorf("%q: verify own samples: %v", tt.exp, err)
+		}
+	}
+	if _, err := Samples(N(), 2); err == nil {
+		t.Errorf("want error for form out of range")
+	}
+	if _, err := Samples(Num(0), -1); err == nil {
+		t.Errorf("want error for negative nplurals")
+	}
+}
+
+func TestParseSamples(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want SampleList
+		err  bool
+	}{
+		{s: "@integer 2~4, 22~24, 32~34, …", want: SampleList{Ranges: []Range{{2, 4}, {22, 24}, {32, 34}}, More: true}},
+		{s: "@integer 0, 5~19, 100, 1000, 10000, 100000, 1000000, … @decimal 0.0~1.5, 10.0, …",
+			want: SampleList{Ranges: []Range{{0, 0}, {5, 19}, {100, 100}, {1000, 1000}, {10000, 10000}, {100000, 100000}, {1000000, 1000000}}, More: true}},
+		{s: "1,3 ~ 5,...", want: SampleList{Ranges: []Range{{1, 1}, {3, 5}}, More: true}},
+		// CLDR 的紧凑写法
+		{s: "@integer 1000000, 1c6, 2c6, 1.5e3~1.6e3, 1000c-3, …",
+			want: SampleList{Ranges: []Range{{1000000, 1000000}, {1000000, 1000000}, {2000000, 2000000}, {1500, 1600}, {1, 1}}, More: true}},
+		{s: "", want: SampleList{}},
+		{s: "@integer 1.5c0", err: true},
+		{s: "@integer c6", err: true},
+		{s: "@integer 1c", err: true},
+		{s: "@integer 1c30", err: true},
+		{s: "@integer 4~2", err: true},
+		{s: "@integer 1, …, 5", err: true},
+		{s: "@integer x", err: true},
+	} {
+		got, err := ParseSamples(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseSamples(%q): err=%v, want err=%v", tt.s, err, tt.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSamples(%q)=%+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestVerifySamples(t *testing.T) {
+	// CLDR 中俄语的样例
+	ru := []string{
+		"@integer 1, 21, 31, 41, 51, 61, 71, 81, 101, 1001, …",
+		"@integer 2~4, 22~24, 32~34, 42~44, 52~54, 62, 102, 1002, …",
+		"@integer 0, 5~19, 100, 1000, 10000, 100000, 1000000, …",
+	}
+	exp, err := Compile(commonRules[7].exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySamples(exp, ru); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	lt, err := Compile(commonRules[6].exp) // 立陶宛语的 5~9 属于形式 1
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mismatch *SampleError
+	if err := VerifySamples(lt, ru); !errors.As(err, &mismatch) || mismatch.N != 5 || mismatch.Form != 2 || mismatch.Got != 1 {
+		t.Errorf("want mismatch at n=5, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package plurals
+
+import (
+	"iter"
+	"math"
+	"slices"
+)
+
+// maxPeriod bounds the period analyze accepts.
+const maxPeriod = 1 << 24
+
+// shape tells how an expression depends on n:
+// f(n) == f(n+period) whenever n >= hi or n+period <= lo,
+// and whenever n and n+period are between the same two cuts.
+type shape struct {
+	lo, hi, period int64
+	cuts           []int64 // 与 n 比较的常量 c 及 c+1, 以及 0, 未排序
+}
+
+// comparable reports whether n in [lo-period, hi+period], which covers every
+// result of a function with this shape, is small enough to be enumerated.
+func (s shape) comparable() bool {
+	return s.within(maxCompare)
+}
+
+// within reports whether [lo-period, hi+period] has at most limit + 1 numbers.
+func (s shape) within(limit uint64) bool {
+	// 先比较周期, 避免 limit-2*period 下溢
+	return uint64(s.period) <= limit/2 &&
+		uint64(s.hi)-uint64(s.lo) <= limit-2*uint64(s.period) &&
+		s.lo >= math.MinInt64+s.period && s.hi <= math.MaxInt64-s.period
+}
+
+// analyze finds the shape of exp. It succeeds when n is only used as
+// `n % c` or compared with a constant c, which covers all the usual rules.
+func analyze(exp Expression) (s shape, ok bool) {
+	s.period = 1
+	var scan func(exp Expression) bool
+	threshold := func(c int64) {
+		// n op c 在 n > c 与 n < c 两侧均为常量
+		s.cuts = append(s.cuts, c)
+		if c < math.MaxInt64 {
+			s.hi = max(s.hi, c+1)
+			s.cuts = append(s.cuts, c+1)
+		} else {
+			s.hi = c
+		}
+		if c > math.MinInt64 {
+			s.lo = min(s.lo, c-1)
+		} else {
+			s.lo = c
+		}
+	}
+	scan = func(exp Expression) bool {
+		exp = unwrap(exp)
+		if p, ok := exp.(*PrimaryNode); ok && p.Type == TokenTypeIDN {
+			return false // 单独出现的 n, 或其他变量
+		}
+		if _, ok := constant(exp); ok {
+			return true
+		}
+		switch e := exp.(type) {
+		case *CompareNode:
+			if c, ok := constant(e.Other); ok && isN(unwrap(e.Exp)) {
+				threshold(c)
+				return true
+			}
+			if c, ok := constant(e.Exp); ok && isN(unwrap(e.Other)) {
+				threshold(c)
+				return true
+			}
+		case *InNode:
+			if isN(unwrap(e.Exp)) {
+				for _, r := range e.Ranges {
+					threshold(r.From)
+					threshold(r.To)
+				}
+				return true
+			}
+		case *BinaryNExp:
+			if isN(unwrap(e.Exp)) {
+				c, ok := constant(e.Other[0])
+				if e.Op[0] != "%" || !ok || c == 0 {
+					return false
+				}
+				if s.period = lcm(s.period, abs(c)); s.period > maxPeriod {
+					return false
+				}
+				for _, other := range e.Other[1:] {
+					if !scan(other) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+		for _, sub := range children(exp) {
+			if !scan(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	if !scan(exp) {
+		return shape{}, false
+	}
+	// n % c 在 0 两侧不是周期的: -1 % 10 == -1, 而 9 % 10 == 9
+	s.cuts = append(s.cuts, 0)
+	return s, true
+}
+
+// pieces yields consecutive spans [from, to] that cover [from, MaxInt64],
+// with f(m) == f(m+period) whenever m and m+period are in the same span.
+func (s shape) pieces(from int64) iter.Seq2[int64, int64] {
+	return func(yield func(int64, int64) bool) {
+		n := from
+		if n <= s.lo {
+			// f(m) == f(m+P) 对 m+P <= s.lo 成立
+			if !yield(n, s.lo) {
+				return
+			}
+			n = s.lo + 1
+		}
+		// 相邻两个阈值之间同样是周期的, 直接跳到下一个阈值
+		cuts := slices.Sorted(slices.Values(s.cuts))
+		for n < s.hi {
+			next := s.hi
+			if i, _ := slices.BinarySearch(cuts, n+1); i < len(cuts) {
+				next = min(next, cuts[i])
+			}
+			if !yield(n, next-1) {
+				return
+			}
+			n = next
+		}
+		// f(m) == f(m+P) 对 m >= s.hi 成立
+		yield(n, math.MaxInt64)
+	}
+}
+
+func isN(exp Expression) bool {
+	p, ok := exp.(*PrimaryNode)
+	return ok && p.Type == TokenTypeIDN && p.Name == ""
+}
+
+// constant evaluates exp if it does not depend on n.
+func constant(exp Expression) (int64, bool) {
+	if exp == nil {
+		return 0, false
+	}
+	hasN := !inspect(exp, func(e Expression) bool { return !isN(e) })
+	if hasN {
+		return 0, false
+	}
+	val, err := exp.Eval(0)
+	return val, err == nil
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func lcm(a, b int64) int64 {
+	if a > maxPeriod || b > maxPeriod || b <= 0 {
+		return maxPeriod + 1
+	}
+	return a / gcd(a, b) * b
+}
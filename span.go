@@ -0,0 +1,85 @@
+package plurals
+
+import "fmt"
+
+// Span is the byte offsets [Start, End) of a node in the compiled source,
+// the zero Span means the node was not parsed, e.g. it was built.
+type Span struct {
+	Start, End int
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("[%d:%d]", s.Start, s.End)
+}
+
+// SpanOf returns the source span of exp.
+func SpanOf(exp Expression) Span {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		return e.Span
+	case *LogicNode:
+		return e.Span
+	case *CompareNode:
+		return e.Span
+	case *BinaryNExp:
+		return e.Span
+	case *UnaryExp:
+		return e.Span
+	case *PrimaryNode:
+		return e.Span
+	case *InNode:
+		return e.Span
+	}
+	return Span{}
+}
+
+func setSpan(exp Expression, s Span) {
+	switch e := exp.(type) {
+	case *TernaryNode:
+		e.Span = s
+	case *LogicNode:
+		e.Span = s
+	case *CompareNode:
+		e.Span = s
+	case *BinaryNExp:
+		e.Span = s
+	case *UnaryExp:
+		e.Span = s
+	case *PrimaryNode:
+		e.Span = s
+	case *InNode:
+		e.Span = s
+	}
+}
+
+// EvalError is an error of evaluating the part of the source at Span,
+// such as ErrDivideZero or an *UnboundError.
+type EvalError struct {
+	Span Span
+	Err  error
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("at column %v: %v", e.Span, e.Err)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// at 在已知位置时为 err 加上位置
+func at(s Span, err error) error {
+	if s == (Span{}) {
+		return err
+	}
+	return &EvalError{Span: s, Err: err}
+}
+
+// divisionAt 返回 BinaryNExp 中第 idx 个运算的位置
+func divisionAt(e *BinaryNExp, idx int) Span {
+	start, end := SpanOf(e.Exp), SpanOf(e.Other[idx])
+	if start == (Span{}) || end == (Span{}) {
+		return Span{}
+	}
+	return Span{Start: start.Start, End: end.End}
+}
@@ -0,0 +1,90 @@
+package plurals
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSpan(t *testing.T) {
+	for _, s := range []string{
+		"n != 1",
+		"  (n % 10 == 1) && n % 100 != 11 ? 0 : !( n >= 2 ) ? 1 : 2 ;",
+		"n % 10 in 2..4, 7 && m not in 12..14 ? 1 : 2",
+		"!!n + 1 - 2 * (3 / n)",
+	} {
+		exp, err := Compile(s, Extended())
+		if err != nil {
+			t.Fatalf("compile %q: %+v", s, err)
+		}
+		// 每个节点对应的源码片段编译后与该节点相同
+		inspect(exp, func(e Expression) bool {
+			span := SpanOf(e)
+			if span == (Span{}) || span.End > len(s) {
+				t.Errorf("%q: node %v has span %v", s, e, span)
+				return false
+			}
+			src := s[span.Start:span.End]
+			if sub, err := Compile(src, Extended()); err != nil || !Equal(sub, e) {
+				t.Errorf("%q: span %v is %q, want %v", s, span, src, e)
+			}
+			return true
+		})
+	}
+}
+
+func TestEvalErrorSpan(t *testing.T) {
+	for _, tt := range []struct {
+		exp  string
+		want string // 出错部分
+	}{
+		{exp: "n == 1 || 10 / (n - n) ? 0 : 1", want: "10 / (n - n)"},
+		{exp: "n % 2 ? 0 : n * 3 % 0", want: "n * 3 % 0"},
+		{exp: "n > m", want: "m"},
+	} {
+		exp, err := Compile(tt.exp, Extended())
+		if err != nil {
+			t.Fatalf("compile %q: %+v", tt.exp, err)
+		}
+		for name, eval := range map[string]func() error{
+			"Eval":    func() error { _, err := exp.Eval(2); return err },
+			"EvalGNU": func() error { _, err := EvalGNU(exp, 2); return err },
+			"EvalEnv": func() error { _, err := EvalEnv(exp, Vars{"n": 2}); return err },
+		} {
+			err := eval()
+			var evalErr *EvalError
+			if !errors.As(err, &evalErr) {
+				t.Errorf("%s(%q): err=%v, want *EvalError", name, tt.exp, err)
+				continue
+			}
+			if got := tt.exp[evalErr.Span.Start:evalErr.Span.End]; got != tt.want {
+				t.Errorf("%s(%q): error at %q, want %q: %v", name, tt.exp, got, tt.want, err)
+			}
+		}
+	}
+	// 包级别的 Eval 与 NewRule 报告的位置对应原始输入, 而不是去掉空白后的字符串
+	for _, s := range []string{"n == 1 || 10 / (n - n) ? 0 : 1", "n==1||10/(n-n)?0:1", "n  ==  1 ||\t10 / ( n-n ) ? 0 : 1"} {
+		r, err := NewRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, err := range map[string]error{
+			"Eval":    second(Eval(s, 2)),
+			"NewRule": second(r.Eval(2)),
+		} {
+			var evalErr *EvalError
+			if !errors.As(err, &evalErr) {
+				t.Errorf("%s(%q): err=%v, want *EvalError", name, s, err)
+				continue
+			}
+			if got := s[evalErr.Span.Start:evalErr.Span.End]; !strings.HasPrefix(got, "10") || !strings.HasSuffix(got, ")") {
+				t.Errorf("%s(%q): error at %q, want the division", name, s, got)
+			}
+		}
+	}
+	if _, err := Div(N(), Num(0)).Eval(1); err != ErrDivideZero {
+		t.Errorf("built expressions have no span, got %v", err)
+	}
+}
+
+func second(_ int64, err error) error { return err }
@@ -13,12 +13,16 @@ const (
 	TokenTypeCMP = "CMP" // > >= < <=
 	TokenTypeMUL = "MUL" // * / %
 	TokenTypeADD = "ADD" // + -
-	TokenTypeIDN = "IDN" // n
+	TokenTypeIDN = "IDN" // n, 扩展语法中也可以是其他变量名
 	TokenTypeQST = "QST" // ?
 	TokenTypeCOL = "COL" // :
 	TokenTypeLPA = "LPA" // (
 	TokenTypeRPA = "RPA" // )
 	TokenTypeCOM = "COM" // ;
+	TokenTypeWSP = "WSP" // 空白, 仅 KeepWhitespace 时输出
+	TokenTypeKWD = "KWD" // in, not in	扩展语法
+	TokenTypeRNG = "RNG" // ..	扩展语法
+	TokenTypeSEP = "SEP" // ,	扩展语法
 )
 
 type Token struct {
@@ -27,6 +31,8 @@ type Token struct {
 	Number int64
 	Start  int
 	End    int
+	Line   int // 从 1 开始
+	Column int // 从 1 开始, 按字节计
 }
 
 func (t Token) String() string {
@@ -42,6 +48,7 @@ func (t Token) String() string {
 // The string following plural is an expression which is using the C language syntax.
 // Exceptions are that no negative numbers are allowed, numbers must be decimal, and the only variable allowed is n.
 // Spaces are allowed in the expression, but backslash-newlines are not.
+// 默认(Strict)严格按照下面的产生式; Lenient 模式额外接受换行、反斜杠续行、大写 N 和整数后缀 1U/1L.
 
 /*
 
@@ -63,12 +70,19 @@ expression                : ternary_expression
 ternary_expression        : logical_or_expression ( '?' expression ':' expression )?
 logical_or_expression     : logical_and_expression ( '||' logical_and_expression )*
 logical_and_expression    : equality_expression ( '&&' equality_expression )*
-equality_expression       : relational_expression ( ('=='|'!=') relational_expression )?
-relational_expression     : additive_expression ( ('>'|'<'|'>='|'<=') additive_expression )?
+equality_expression       : relational_expression ( ('=='|'!=') relational_expression )*
+relational_expression     : additive_expression ( ('>'|'<'|'>='|'<=') additive_expression )*
 additive_expression       : multiplicative_expression ( ('+'|'-') multiplicative_expression )*
 multiplicative_expression : unary_expression ( ('*'|'/'|'%') unary_expression)*
-unary_expression          : '!'? primary_expression
+unary_expression          : '!'* primary_expression
 primary_expression        : 'n'
                           | NUMBER
                           | '(' expression ')'
+
+扩展语法(Extended), 与关系运算符同一优先级:
+relational_expression     : additive_expression ( ('>'|'<'|'>='|'<=') additive_expression
+                                                | ('in'|'not' 'in') range ( ',' range )* )*
+range                     : NUMBER ( '..' NUMBER )?
+primary_expression        : IDENTIFIER	变量, 如 n, m, k
+                          | ...
 */
@@ -0,0 +1,129 @@
+package plurals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trace is the record of evaluating an expression for one n, see EvalTrace.
+type Trace struct {
+	N     int64
+	Value int64
+	Err   error
+	Root  *TraceNode
+}
+
+// TraceNode is the value of one evaluated sub expression. The operands skipped
+// by a ternary expression or a short-circuit are not recorded, Note tells
+// which were taken or skipped.
+type TraceNode struct {
+	Expr     Expression
+	Value    int64
+	Err      error
+	Note     string
+	Children []*TraceNode
+}
+
+// EvalTrace evaluates exp like exp.Eval(n), recording every sub expression.
+// It hooks into the evaluator of EvalEnv, so Eval itself is not slowed down.
+func EvalTrace(exp Expression, n int64) *Trace {
+	t := &tracer{}
+	e := evaluator[int64]{d: int64Domain{}, n: n, hook: t}
+	val, err := e.eval(exp)
+	return &Trace{N: n, Value: val, Err: err, Root: t.root}
+}
+
+// tracer builds the TraceNode tree, leaving out the wrapper nodes.
+type tracer struct {
+	root  *TraceNode
+	stack []*TraceNode
+}
+
+func (t *tracer) enter(exp Expression) {
+	if unwrap(exp) != exp {
+		return
+	}
+	node := &TraceNode{Expr: exp}
+	if top := len(t.stack) - 1; top >= 0 {
+		t.stack[top].Children = append(t.stack[top].Children, node)
+	} else {
+		t.root = node
+	}
+	t.stack = append(t.stack, node)
+}
+
+func (t *tracer) leave(exp Expression, val int64, err error) {
+	if unwrap(exp) != exp {
+		return
+	}
+	node := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	node.Value, node.Err = val, err
+	switch e := exp.(type) {
+	case *TernaryNode:
+		if len(node.Children) == 2 {
+			taken := fmt.Sprint(i2b(node.Children[0].Value))
+			node.Note = fmt.Sprintf("condition is %s, took the %s branch", taken, taken)
+		}
+	case *LogicNode:
+		if err == nil && len(node.Children) < len(e.Exps) {
+			node.Note = fmt.Sprintf("short-circuit, skipped %d of %d operands", len(e.Exps)-len(node.Children), len(e.Exps))
+		}
+	}
+}
+
+// String explains the result in one line, following the ternary branches
+// taken, e.g. `n=111: n % 10 == 1 && n % 100 != 11 is false, ... so form 2`.
+func (t *Trace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "n=%d:", t.N)
+	node := t.Root
+	for {
+		if _, ok := node.Expr.(*TernaryNode); !ok || len(node.Children) < 2 {
+			break
+		}
+		cond := node.Children[0]
+		fmt.Fprintf(&sb, " %v is %v,", cond.Expr, i2b(cond.Value))
+		node = node.Children[1]
+	}
+	if _, ok := node.Expr.(*TernaryNode); ok {
+		// 条件出错
+		node = node.Children[0]
+	}
+	if t.Err != nil {
+		fmt.Fprintf(&sb, " %v fails: %v", node.Expr, t.Err)
+		return sb.String()
+	}
+	if node != t.Root {
+		sb.WriteString(" so")
+	}
+	fmt.Fprintf(&sb, " form %d", t.Value)
+	return sb.String()
+}
+
+// Tree renders every recorded sub expression with its value, indented by
+// depth, numbers and n are left out.
+func (t *Trace) Tree() string {
+	var sb strings.Builder
+	var walk func(node *TraceNode, depth int)
+	walk = func(node *TraceNode, depth int) {
+		if isNum(node.Expr) || isN(node.Expr) {
+			return
+		}
+		fmt.Fprintf(&sb, "%s%v = ", strings.Repeat("  ", depth), node.Expr)
+		if node.Err != nil {
+			fmt.Fprintf(&sb, "error: %v", node.Err)
+		} else {
+			fmt.Fprintf(&sb, "%d", node.Value)
+		}
+		if node.Note != "" {
+			fmt.Fprintf(&sb, "\t// %s", node.Note)
+		}
+		sb.WriteByte('\n')
+		for _, child := range node.Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(t.Root, 0)
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+package plurals
+
+import "testing"
+
+func TestEvalTrace(t *testing.T) {
+	lt, err := Compile(commonRules[6].exp) // Lithuanian
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := EvalTrace(lt, 111)
+	if want := "n=111: n % 10 == 1 && n % 100 != 11 is false, " +
+		"n % 10 >= 2 && ( n % 100 < 10 || n % 100 >= 20 ) is false, so form 2"; tr.String() != want {
+		t.Errorf("got  %q\nwant %q", tr, want)
+	}
+	if want := `n % 10 == 1 && n % 100 != 11 ? 0 : n % 10 >= 2 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2 = 2	// condition is false, took the false branch
+  n % 10 == 1 && n % 100 != 11 = 0
+    n % 10 == 1 = 1
+      n % 10 = 1
+    n % 100 != 11 = 0
+      n % 100 = 11
+  n % 10 >= 2 && ( n % 100 < 10 || n % 100 >= 20 ) ? 1 : 2 = 2	// condition is false, took the false branch
+    n % 10 >= 2 && ( n % 100 < 10 || n % 100 >= 20 ) = 0	// short-circuit, skipped 1 of 2 operands
+      n % 10 >= 2 = 0
+        n % 10 = 1
+`; tr.Tree() != want {
+		t.Errorf("got\n%s\nwant\n%s", tr.Tree(), want)
+	}
+
+	for _, tt := range []struct {
+		exp  string
+		n    int64
+		want string
+	}{
+		{exp: "n != 1", n: 1, want: "n=1: form 0"},
+		{exp: "n == 1 ? 0 : 1", n: 1, want: "n=1: n == 1 is true, so form 0"},
+		{exp: "n == 0 || 10 / (n - 1) > 1 ? 1 : 0", n: 1,
+			want: "n=1: n == 0 || 10 / ( n - 1 ) > 1 fails: at column [10:22]: divide zero"},
+	} {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := EvalTrace(exp, tt.n).String(); got != tt.want {
+			t.Errorf("got  %q\nwant %q", got, tt.want)
+		}
+	}
+
+	// 与 Eval 结果一致
+	for _, tt := range cConformance {
+		exp, err := Compile(tt.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for n := int64(-5); n < 300; n++ {
+			want, wantErr := exp.Eval(n)
+			tr := EvalTrace(exp, n)
+			if tr.Value != want || (tr.Err == nil) != (wantErr == nil) {
+				t.Errorf("%q n=%d: trace %d, %v, want %d, %v", tt.exp, n, tr.Value, tr.Err, want, wantErr)
+				break
+			}
+		}
+	}
+}